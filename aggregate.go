@@ -0,0 +1,175 @@
+package json_filter
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	_ NumberNoder    = (*NodeAggregate)(nil)
+	_ InterfaceNoder = (*NodeAggregate)(nil)
+)
+
+const (
+	AggregateCount = "count"
+	AggregateSum   = "sum"
+	AggregateAvg   = "avg"
+	AggregateMin   = "min"
+	AggregateMax   = "max"
+)
+
+func isAggregateFunction(name string) bool {
+	switch name {
+	case AggregateCount, AggregateSum, AggregateAvg, AggregateMin, AggregateMax:
+		return true
+	}
+	return false
+}
+
+//NodeAggregate 聚合函数，计算时从分组的Getter中按Key取出聚合结果
+type NodeAggregate struct {
+	Func string
+	//Arg 为nil时表示count(*)
+	Arg InterfaceNoder
+	//Distinct 是否为count(distinct x)
+	Distinct bool
+	Key      string
+}
+
+func (n NodeAggregate) Type() NodeType {
+	return NodeTypeAggregate
+}
+
+func (n NodeAggregate) Children() []Noder {
+	if n.Arg == nil {
+		return nil
+	}
+	return []Noder{n.Arg}
+}
+
+func (n NodeAggregate) Interface(getter Getter) (interface{}, error) {
+	return getter.Get(n.Key)
+}
+
+func (n NodeAggregate) Number(getter Getter) (decimal.Decimal, error) {
+	data, err := getter.Get(n.Key)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return toNumber(data)
+}
+
+//newAccumulator 创建该聚合函数的累加器，approximate为true时count(distinct x)使用HyperLogLog估算
+func (n NodeAggregate) newAccumulator(approximate bool) accumulator {
+	if n.Distinct {
+		return newCountDistinctAccumulator(approximate)
+	}
+	switch n.Func {
+	case AggregateCount:
+		return &countAccumulator{}
+	case AggregateSum:
+		return &sumAccumulator{}
+	case AggregateAvg:
+		return &sumAccumulator{avg: true}
+	case AggregateMin:
+		return &extremeAccumulator{sign: -1}
+	default:
+		return &extremeAccumulator{sign: 1}
+	}
+}
+
+//argValue 计算一行数据中聚合函数参数的值，为nil时忽略该行，count(*)时总是为true，
+//sum和avg的参数转换为数值，count(distinct x)的参数编码为字符串，
+//先计算所有聚合函数的参数再累加，某一行出错时整行跳过，不会只累加一部分
+func (n NodeAggregate) argValue(getter Getter) (interface{}, error) {
+	if n.Arg == nil {
+		return true, nil
+	}
+	data, err := n.Arg.Interface(getter)
+	if err != nil || data == nil {
+		return data, err
+	}
+	switch {
+	case n.Distinct:
+		return encodeValues([]interface{}{data})
+	case n.Func == AggregateSum || n.Func == AggregateAvg:
+		d, err := toNumber(data)
+		if err != nil {
+			return nil, fmt.Errorf("%v is not a number: %w", data, err)
+		}
+		return d, nil
+	}
+	return data, nil
+}
+
+//accumulator 聚合函数的累加器，每个分组一个，Add的参数为argValue的结果，不为nil
+type accumulator interface {
+	Add(interface{})
+	Result() interface{}
+}
+
+type countAccumulator struct {
+	count int64
+}
+
+func (a *countAccumulator) Add(interface{}) {
+	a.count++
+}
+
+func (a *countAccumulator) Result() interface{} {
+	return numberValue(decimal.NewFromInt(a.count))
+}
+
+//sumAccumulator 计算sum和avg，忽略null
+type sumAccumulator struct {
+	avg   bool
+	sum   decimal.Decimal
+	count int64
+}
+
+func (a *sumAccumulator) Add(data interface{}) {
+	a.sum = a.sum.Add(data.(decimal.Decimal))
+	a.count++
+}
+
+func (a *sumAccumulator) Result() interface{} {
+	if a.count == 0 {
+		return nil
+	}
+	result := a.sum
+	if a.avg {
+		result = result.Div(decimal.NewFromInt(a.count))
+	}
+	return numberValue(result)
+}
+
+//extremeAccumulator 计算min(sign=-1)和max(sign=1)，忽略null
+type extremeAccumulator struct {
+	sign  int
+	value interface{}
+}
+
+func (a *extremeAccumulator) Add(data interface{}) {
+	if a.value == nil || compareValues(data, a.value)*a.sign > 0 {
+		a.value = data
+	}
+}
+
+func (a *extremeAccumulator) Result() interface{} {
+	return a.value
+}
+
+//uniqueAggregates 去掉重复的聚合函数
+func uniqueAggregates(aggs []*NodeAggregate) []*NodeAggregate {
+	seen := make(map[string]bool)
+	result := make([]*NodeAggregate, 0, len(aggs))
+	for _, agg := range aggs {
+		if seen[agg.Key] {
+			continue
+		}
+		seen[agg.Key] = true
+		result = append(result, agg)
+	}
+	return result
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"time"
 
 	"github.com/spf13/pflag"
 
@@ -17,17 +18,27 @@ var (
 )
 
 var (
-	sql          string
-	sqlFile      string
-	errorOutput  string
-	resultOutput string
+	sql           string
+	sqlFile       string
+	errorOutput   string
+	resultOutputs []string
+	sortMemory    int
+	tempDir       string
+	approximate   bool
+	nested        bool
+	timezone      string
 )
 
 func init() {
 	pflag.StringVarP(&sql, "sql", "q", "", "sql")
 	pflag.StringVarP(&sqlFile, "sql_file", "f", "", "sql file")
 	pflag.StringVarP(&errorOutput, "error_output", "", "", "error output")
-	pflag.StringVarP(&resultOutput, "output", "o", "", "output")
+	pflag.StringArrayVarP(&resultOutputs, "output", "o", nil, "output, repeat once per statement when the sql has several statements, - for stdout")
+	pflag.IntVarP(&sortMemory, "sort_memory", "", 0, "max bytes buffered in memory by order by before spilling to temp files")
+	pflag.StringVarP(&tempDir, "temp_dir", "", "", "temp dir for order by")
+	pflag.BoolVarP(&nested, "nested", "", false, "output nested objects instead of dotted keys")
+	pflag.BoolVarP(&approximate, "approx_distinct", "", false, "use bounded memory approximate algorithms for distinct")
+	pflag.StringVarP(&timezone, "timezone", "", "", "timezone for times without zone, e.g. Asia/Shanghai or UTC, default local")
 }
 
 func main() {
@@ -64,20 +75,6 @@ func main() {
 		r = io.MultiReader(files...)
 	}
 
-	// output
-	var w io.Writer
-	if resultOutput != "" {
-		resultFile, err := os.Create(resultOutput)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		defer resultFile.Close()
-		w = resultFile
-	} else {
-		w = os.Stdout
-	}
-
 	// error output
 	var errWriter io.Writer
 	if errorOutput != "" {
@@ -92,21 +89,89 @@ func main() {
 		errWriter = os.Stderr
 	}
 
-	filter, err := json_filter.NewJSONFilterWithConfig(json_filter.FilterConfig{
-		SQL:       sql,
-		ErrWriter: errWriter,
-		Reader:    r,
-	})
+	// timezone
+	loc := time.Local
+	if timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(timezone); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	cfg := json_filter.FilterConfig{
+		SQL:                 sql,
+		ErrWriter:           errWriter,
+		Reader:              r,
+		SortMemoryLimit:     sortMemory,
+		TempDir:             tempDir,
+		ApproximateDistinct: approximate,
+		NestedOutput:        nested,
+		Location:            loc,
+	}
+	stmts, err := json_filter.ParseStatements(sql)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exitWithError(err)
 	}
 
+	// output
+	if len(stmts) > 1 || len(resultOutputs) > 1 {
+		if len(resultOutputs) != len(stmts) {
+			exitWithError(fmt.Errorf("sql has %d statements, each needs its own --output, got %d", len(stmts), len(resultOutputs)))
+		}
+		outputs := make([]io.Writer, 0, len(resultOutputs))
+		for _, output := range resultOutputs {
+			w, err := createOutput(output)
+			if err != nil {
+				exitWithError(err)
+			}
+			defer w.Close()
+			outputs = append(outputs, w)
+		}
+		if err := json_filter.RunStatements(cfg, outputs); err != nil {
+			exitWithError(err)
+		}
+		return
+	}
+	var w io.WriteCloser = os.Stdout
+	if len(resultOutputs) == 1 {
+		w, err = createOutput(resultOutputs[0])
+		if err != nil {
+			exitWithError(err)
+		}
+		defer w.Close()
+	}
+
+	filter, err := json_filter.NewJSONFilterWithConfig(cfg)
+	if err != nil {
+		exitWithError(err)
+	}
+	defer filter.Close()
+
 	for filter.Next() {
 		line, err := filter.GetData()
 		if err != nil {
-			fmt.Println(err)
+			fmt.Fprintln(errWriter, err)
+			continue
 		}
 		fmt.Fprintln(w, string(line))
 	}
 }
+
+//createOutput 创建结果输出的文件，-表示标准输出
+func createOutput(name string) (io.WriteCloser, error) {
+	if name == "-" {
+		return os.Stdout, nil
+	}
+	return os.Create(name)
+}
+
+//exitWithError 输出错误并退出，语法错误时同时标出出错的位置
+func exitWithError(err error) {
+	fmt.Println(err)
+	var syntaxErr *json_filter.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		fmt.Println(syntaxErr.Snippet())
+	}
+	os.Exit(1)
+}
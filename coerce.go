@@ -0,0 +1,270 @@
+package json_filter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+//compareOrdered 按比较规则比较两个值，ok为false时两个值不能比较(如有一个为null)，
+//所有的比较运算、in、between、case、greatest、least都使用这里的规则:
+//   - 有一边是时间，或者一边是时间字符串另一边是数字或时间字符串时，都转换为时间比较
+//   - 数字与数字按数值比较
+//   - 字符串与字符串按字典序比较
+//   - 数字与字符串比较时字符串需要能转换为数字，否则不能比较
+//   - bool与bool比较时false < true，与字符串比较时字符串需要是true或false(不区分大小写)
+//   - 时间间隔与时间间隔按长短比较
+//   - 数组与数组、对象与对象按json文本比较
+//   - 其他情况都不能比较
+func compareOrdered(a, b interface{}, loc *time.Location) (int, bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+	if x, y, ok := timeOperands(a, b, loc); ok {
+		return compareTime(x, y), true
+	}
+	switch x := a.(type) {
+	case string:
+		switch y := b.(type) {
+		case string:
+			return strings.Compare(x, y), true
+		case bool:
+			xb, ok := parseBoolString(x)
+			return compareBool(xb, y), ok
+		}
+		if isNumberValue(b) {
+			return compareNumbers(x, b)
+		}
+	case bool:
+		switch y := b.(type) {
+		case bool:
+			return compareBool(x, y), true
+		case string:
+			yb, ok := parseBoolString(y)
+			return compareBool(x, yb), ok
+		}
+	case Interval:
+		if y, ok := b.(Interval); ok {
+			return compareValues(x, y), true
+		}
+	case map[string]interface{}, []interface{}:
+		if valueRank(a) == valueRank(b) {
+			return compareValues(a, b), true
+		}
+	default:
+		if _, ok := b.(string); ok || isNumberValue(b) {
+			return compareNumbers(a, b)
+		}
+	}
+	return 0, false
+}
+
+//compareNumbers 将两个值都转换为数字比较，不能转换时ok为false
+func compareNumbers(a, b interface{}) (int, bool) {
+	if !isNumberValue(a) && !isNumberValue(b) {
+		return 0, false
+	}
+	x, err := toNumber(a)
+	if err != nil {
+		return 0, false
+	}
+	y, err := toNumber(b)
+	if err != nil {
+		return 0, false
+	}
+	return x.Cmp(y), true
+}
+
+func compareBool(x, y bool) int {
+	switch {
+	case x == y:
+		return 0
+	case !x:
+		return -1
+	}
+	return 1
+}
+
+//parseBoolString 将true、false(不区分大小写)转换为bool
+func parseBoolString(s string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case KeywordTrue:
+		return true, true
+	case KeywordFalse:
+		return false, true
+	}
+	return false, false
+}
+
+//equalValues 按比较规则判断两个值是否相等，不能比较的值不相等
+func equalValues(a, b interface{}, loc *time.Location) bool {
+	c, ok := compareOrdered(a, b, loc)
+	return ok && c == 0
+}
+
+//compareNodes 计算两个节点的值并按比较规则比较大小，有一边为null时null为true
+func compareNodes(left, right InterfaceNoder, getter Getter) (c int, ok bool, null bool, err error) {
+	a, err := nodeValue(left, getter)
+	if err != nil || a == nil {
+		return 0, false, a == nil, err
+	}
+	b, err := nodeValue(right, getter)
+	if err != nil || b == nil {
+		return 0, false, b == nil, err
+	}
+	c, ok = compareOrdered(a, b, getterLocation(getter))
+	return c, ok, false, nil
+}
+
+//castTypes cast支持的类型及其别名
+var castTypes = map[string]string{
+	"int":       "int",
+	"integer":   "int",
+	"bigint":    "int",
+	"float":     "float",
+	"double":    "float",
+	"decimal":   "float",
+	"number":    "float",
+	"string":    "string",
+	"varchar":   "string",
+	"text":      "string",
+	"bool":      "bool",
+	"boolean":   "bool",
+	"timestamp": "timestamp",
+	"datetime":  "timestamp",
+	"time":      "timestamp",
+	"json":      "json",
+}
+
+//castValue 将值转换为指定的类型，null转换后仍为null
+func castValue(data interface{}, to string, loc *time.Location) (interface{}, error) {
+	if data == nil {
+		return nil, nil
+	}
+	switch to {
+	case "int", "float":
+		d, err := castNumber(data)
+		if err != nil {
+			break
+		}
+		if to == "int" {
+			d = d.Truncate(0)
+		}
+		return numberValue(d), nil
+	case "string":
+		switch data.(type) {
+		case map[string]interface{}, []interface{}:
+			bs, err := exactJSON.Marshal(data)
+			if err != nil {
+				return nil, err
+			}
+			return string(bs), nil
+		}
+		if s, err := toString(data); err == nil {
+			return s, nil
+		}
+	case "bool":
+		if b, ok := castBool(data); ok {
+			return b, nil
+		}
+	case "timestamp":
+		if t, err := toTime(data, loc); err == nil {
+			return t, nil
+		}
+	case "json":
+		s, ok := data.(string)
+		if !ok {
+			return data, nil
+		}
+		var v interface{}
+		if err := exactJSON.UnmarshalFromString(s, &v); err == nil {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot cast %v to %s", data, to)
+}
+
+//castNumber 数字、数字字符串、bool(1和0)、时间(unix时间戳)转换为数字
+func castNumber(data interface{}) (decimal.Decimal, error) {
+	switch v := data.(type) {
+	case bool:
+		if v {
+			return decimal.New(1, 0), nil
+		}
+		return decimal.Zero, nil
+	case time.Time:
+		return unixSeconds(v), nil
+	case Interval:
+		return decimal.New(int64(v), -9), nil
+	}
+	return toNumber(data)
+}
+
+//castBool bool、true/false/1/0字符串、数字(不为0时为true)转换为bool
+func castBool(data interface{}) (bool, bool) {
+	switch v := data.(type) {
+	case bool:
+		return v, true
+	case string:
+		if b, ok := parseBoolString(v); ok {
+			return b, true
+		}
+		switch strings.TrimSpace(v) {
+		case "1":
+			return true, true
+		case "0":
+			return false, true
+		}
+		return false, false
+	}
+	if isNumberValue(data) {
+		d, err := toNumber(data)
+		return !d.IsZero(), err == nil
+	}
+	return false, false
+}
+
+//NodeCast cast(x as type)和try_cast(x as type)，cast转换失败时报错，try_cast转换失败时为null
+type NodeCast struct {
+	Value InterfaceNoder
+	To    string
+	Try   bool
+}
+
+func (n NodeCast) Type() NodeType {
+	return NodeTypeCast
+}
+
+func (n NodeCast) Children() []Noder {
+	return []Noder{n.Value}
+}
+
+func (n NodeCast) Interface(getter Getter) (interface{}, error) {
+	data, err := n.Value.Interface(getter)
+	if err != nil {
+		return nil, err
+	}
+	v, err := castValue(data, n.To, getterLocation(getter))
+	if err != nil && n.Try {
+		return nil, nil
+	}
+	return v, err
+}
+
+func (n NodeCast) Number(getter Getter) (decimal.Decimal, error) {
+	data, err := n.Interface(getter)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return toNumber(data)
+}
+
+func (n NodeCast) Bool(getter Getter) (bool, error) {
+	data, err := n.Interface(getter)
+	if err != nil {
+		return false, err
+	}
+	return resultBool("cast", data)
+}
@@ -0,0 +1,72 @@
+package json_filter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCast(t *testing.T) {
+	getter := zonedGetter{
+		mapGetter{"n": Number("2"), "s": "12", "f": "1.5", "b": true, "bs": "true", "bad": "abc", "ts": "2024-01-02T03:04:05Z"},
+		time.UTC,
+	}
+	checkValues(t, getter, []valueCase{
+		{"cast(s as int)", "12"},
+		{"cast(s as integer) + 1", "13"},
+		{"cast(f as int)", "1"},
+		{"cast(f as decimal)", "1.5"},
+		{"cast(f as double)", "1.5"},
+		{"cast(n as string)", "2"},
+		{"cast(n as string) || 'x'", "2x"},
+		{"cast(b as int)", "1"},
+		{"cast(b as string)", "true"},
+		{"cast(bs as bool)", "true"},
+		{"cast(1 as bool)", "true"},
+		{"cast(0 as boolean)", "false"},
+		{"format_time(cast(ts as timestamp), '2006-01-02 15:04:05')", "2024-01-02 03:04:05"},
+		{"cast(none as int)", "<nil>"},
+		{"try_cast(bad as int)", "<nil>"},
+		{"try_cast('x' as timestamp)", "<nil>"},
+		{"try_cast(f as int)", "1"},
+		{"coalesce(try_cast(bad as int), -1)", "-1"},
+	})
+	checkBools(t, getter, []boolCase{
+		{"try_cast(bad as int) is null", true},
+		{"cast(s as int) = 12", true},
+		{"cast(s as string) = '12'", true},
+	})
+	for _, expr := range []string{"cast(bad as int)", "cast(s as bool)", "cast('x' as timestamp)"} {
+		stmt, err := ParseStatement("select " + expr + " from t")
+		if err != nil {
+			t.Fatalf("%s: %v", expr, err)
+		}
+		if _, err := stmt.Items[0].Expr.Interface(getter); err == nil {
+			t.Errorf("%s: want an error", expr)
+		}
+	}
+	for _, sql := range []string{"select cast(a as foo) from t", "select cast(a) from t"} {
+		if _, err := ParseStatement(sql); err == nil {
+			t.Errorf("%s: want an error", sql)
+		}
+	}
+}
+
+//TestCastErrorPolicy cast转换失败时报错并跳过该行，try_cast转换失败时为null
+func TestCastErrorPolicy(t *testing.T) {
+	input := `{"id":1,"v":"10"}
+{"id":2,"v":"abc"}
+{"id":3,"v":"30"}
+`
+	rows, errs := runSQL(t, "select id from t where cast(v as int) > 5", input)
+	if want := []string{`{"id":1}`, `{"id":3}`}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("cast: rows %q, want %q", rows, want)
+	}
+	if want := []string{`check line error: cannot cast abc to int: {"id":2,"v":"abc"}`}; !reflect.DeepEqual(errs, want) {
+		t.Errorf("cast: errors %q, want %q", errs, want)
+	}
+	rows, errs = runSQL(t, "select id, try_cast(v as int) as n from t", input)
+	if want := []string{`{"id":1,"n":10}`, `{"id":2,"n":null}`, `{"id":3,"n":30}`}; !reflect.DeepEqual(rows, want) || len(errs) != 0 {
+		t.Errorf("try_cast: rows %q errors %q, want %q", rows, errs, want)
+	}
+}
@@ -0,0 +1,263 @@
+package json_filter
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	_ InterfaceNoder = (*NodeCase)(nil)
+	_ NumberNoder    = (*NodeCase)(nil)
+	_ BoolNoder      = (*NodeCase)(nil)
+	_ InterfaceNoder = (*NodeCoalesce)(nil)
+	_ NumberNoder    = (*NodeCoalesce)(nil)
+	_ BoolNoder      = (*NodeCoalesce)(nil)
+	_ InterfaceNoder = (*NodeNullIf)(nil)
+	_ NumberNoder    = (*NodeNullIf)(nil)
+	_ BoolNoder      = (*NodeNullIf)(nil)
+	_ InterfaceNoder = (*NodeIf)(nil)
+	_ NumberNoder    = (*NodeIf)(nil)
+	_ BoolNoder      = (*NodeIf)(nil)
+)
+
+//resultBool 条件表达式的结果直接作为条件时必须是bool，为null时为false
+func resultBool(name string, data interface{}) (bool, error) {
+	switch b := data.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return b, nil
+	}
+	return false, fmt.Errorf("%s returns %v, not a boolean", name, data)
+}
+
+//CaseWhen case中的一个when分支，简单case使用Value，搜索case使用Cond
+type CaseWhen struct {
+	Cond   BoolNoder
+	Value  InterfaceNoder
+	Result InterfaceNoder
+}
+
+//NodeCase case when cond then x ... else y end，或者case v when a then x ... else y end，
+//没有匹配的分支且没有else时为null
+type NodeCase struct {
+	//Operand 简单case中case后面的值，为nil时为搜索case
+	Operand InterfaceNoder
+	Whens   []CaseWhen
+	Else    InterfaceNoder
+}
+
+func (n NodeCase) Type() NodeType {
+	return NodeTypeCase
+}
+
+func (n NodeCase) Children() []Noder {
+	children := make([]Noder, 0, 2*len(n.Whens)+2)
+	if n.Operand != nil {
+		children = append(children, n.Operand)
+	}
+	for _, when := range n.Whens {
+		if when.Cond != nil {
+			children = append(children, when.Cond)
+		} else {
+			children = append(children, when.Value)
+		}
+		children = append(children, when.Result)
+	}
+	if n.Else != nil {
+		children = append(children, n.Else)
+	}
+	return children
+}
+
+func (n NodeCase) Interface(getter Getter) (interface{}, error) {
+	var operand interface{}
+	if n.Operand != nil {
+		var err error
+		if operand, err = n.Operand.Interface(getter); err != nil {
+			return nil, err
+		}
+	}
+	for _, when := range n.Whens {
+		matched, err := n.match(when, operand, getter)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			return when.Result.Interface(getter)
+		}
+	}
+	if n.Else == nil {
+		return nil, nil
+	}
+	return n.Else.Interface(getter)
+}
+
+//match 判断分支是否匹配，简单case中null与任何值都不相等
+func (n NodeCase) match(when CaseWhen, operand interface{}, getter Getter) (bool, error) {
+	if when.Cond != nil {
+		return when.Cond.Bool(getter)
+	}
+	if operand == nil {
+		return false, nil
+	}
+	v, err := when.Value.Interface(getter)
+	if err != nil || v == nil {
+		return false, err
+	}
+	return equalValues(operand, v, getterLocation(getter)), nil
+}
+
+func (n NodeCase) Number(getter Getter) (decimal.Decimal, error) {
+	data, err := n.Interface(getter)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return toNumber(data)
+}
+
+func (n NodeCase) Bool(getter Getter) (bool, error) {
+	data, err := n.Interface(getter)
+	if err != nil {
+		return false, err
+	}
+	return resultBool("case", data)
+}
+
+//NodeCoalesce coalesce(a, b, ...)，返回第一个不为null的值，后面的参数不再计算
+type NodeCoalesce struct {
+	Args []InterfaceNoder
+}
+
+func (n NodeCoalesce) Type() NodeType {
+	return NodeTypeCoalesce
+}
+
+func (n NodeCoalesce) Children() []Noder {
+	children := make([]Noder, len(n.Args))
+	for i, arg := range n.Args {
+		children[i] = arg
+	}
+	return children
+}
+
+func (n NodeCoalesce) Interface(getter Getter) (interface{}, error) {
+	for _, arg := range n.Args {
+		v, err := arg.Interface(getter)
+		if err != nil || v != nil {
+			return v, err
+		}
+	}
+	return nil, nil
+}
+
+func (n NodeCoalesce) Number(getter Getter) (decimal.Decimal, error) {
+	data, err := n.Interface(getter)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return toNumber(data)
+}
+
+func (n NodeCoalesce) Bool(getter Getter) (bool, error) {
+	data, err := n.Interface(getter)
+	if err != nil {
+		return false, err
+	}
+	return resultBool("coalesce", data)
+}
+
+//NodeNullIf nullif(a, b)，a与b相等时为null，否则为a
+type NodeNullIf struct {
+	Value InterfaceNoder
+	Null  InterfaceNoder
+}
+
+func (n NodeNullIf) Type() NodeType {
+	return NodeTypeNullIf
+}
+
+func (n NodeNullIf) Children() []Noder {
+	return []Noder{n.Value, n.Null}
+}
+
+func (n NodeNullIf) Interface(getter Getter) (interface{}, error) {
+	v, err := n.Value.Interface(getter)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	null, err := n.Null.Interface(getter)
+	if err != nil {
+		return nil, err
+	}
+	if null != nil && equalValues(v, null, getterLocation(getter)) {
+		return nil, nil
+	}
+	return v, nil
+}
+
+func (n NodeNullIf) Number(getter Getter) (decimal.Decimal, error) {
+	data, err := n.Interface(getter)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return toNumber(data)
+}
+
+func (n NodeNullIf) Bool(getter Getter) (bool, error) {
+	data, err := n.Interface(getter)
+	if err != nil {
+		return false, err
+	}
+	return resultBool("nullif", data)
+}
+
+//NodeIf if(cond, a[, b])，条件成立时为a，否则为b，没有b时为null
+type NodeIf struct {
+	Cond BoolNoder
+	Then InterfaceNoder
+	Else InterfaceNoder
+}
+
+func (n NodeIf) Type() NodeType {
+	return NodeTypeIf
+}
+
+func (n NodeIf) Children() []Noder {
+	children := []Noder{n.Cond, n.Then}
+	if n.Else != nil {
+		children = append(children, n.Else)
+	}
+	return children
+}
+
+func (n NodeIf) Interface(getter Getter) (interface{}, error) {
+	ok, err := n.Cond.Bool(getter)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return n.Then.Interface(getter)
+	}
+	if n.Else == nil {
+		return nil, nil
+	}
+	return n.Else.Interface(getter)
+}
+
+func (n NodeIf) Number(getter Getter) (decimal.Decimal, error) {
+	data, err := n.Interface(getter)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return toNumber(data)
+}
+
+func (n NodeIf) Bool(getter Getter) (bool, error) {
+	data, err := n.Interface(getter)
+	if err != nil {
+		return false, err
+	}
+	return resultBool("if", data)
+}
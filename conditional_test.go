@@ -0,0 +1,46 @@
+package json_filter
+
+import (
+	"testing"
+)
+
+func TestConditional(t *testing.T) {
+	getter := mapGetter{"status": Number("503"), "uid": "u1", "user_id": nil, "level": "", "n": Number("2"), "s": "12", "b": true}
+	checkValues(t, getter, []valueCase{
+		{"case when status >= 500 then 'error' else 'ok' end", "error"},
+		{"case when status < 500 then 'ok' end", "<nil>"},
+		{"case when status < 500 then 'ok' when status < 600 then '5xx' end", "5xx"},
+		{"case n when 1 then 'one' when 2 then 'two' end", "two"},
+		{"case s when 12 then 'num' else 'no' end", "num"},
+		{"case when none = 1 then 'a' else 'b' end", "b"},
+		{"coalesce(user_id, uid)", "u1"},
+		{"coalesce(user_id, none)", "<nil>"},
+		{"coalesce(user_id, none, 'd')", "d"},
+		{"nullif(level, '')", "<nil>"},
+		{"nullif(uid, '')", "u1"},
+		{"if(status > 500, 'bad', 'good')", "bad"},
+		{"if(status > 600, 'bad')", "<nil>"},
+		{"if(b, 1, 0)", "1"},
+		//只计算用到的分支
+		{"coalesce(2, 1/0)", "2"},
+		{"if(true, 1, 1/0)", "1"},
+		{"case when n = 2 then 1 else 1/0 end", "1"},
+	})
+	checkBools(t, getter, []boolCase{
+		{"case when b then true else false end", true},
+		{"coalesce(user_id, b)", true},
+		{"if(b, false, true)", false},
+		{"coalesce(user_id, uid) = 'u1'", true},
+	})
+	for _, sql := range []string{
+		"select coalesce() from t",
+		"select nullif(a) from t",
+		"select if(a) from t",
+		"select case when a then 1 from t",
+		"select case a then 1 end from t",
+	} {
+		if _, err := ParseStatement(sql); err == nil {
+			t.Errorf("%s: want an error", sql)
+		}
+	}
+}
@@ -1,23 +1,66 @@
 package json_filter
 
-var keywords = map[string]string{
-	KeywordIn:   KeywordIn,
-	KeywordNot:  KeywordNot,
-	KeywordLike: KeywordLike,
-	KeywordAnd:  KeywordAnd,
-	KeywordOr:   KeywordOr,
-	KeywordIs:   KeywordIs,
-	KeywordNULL: KeywordNULL,
+//keywords sql中的关键字，值为true的是保留字，不能直接作为字段名，
+//值为false的只在特定的位置作为关键字，如div、interval、escape、missing、case、when、then、else、end
+var keywords = map[string]bool{
+	"select":        true,
+	"distinct":      true,
+	"from":          true,
+	"where":         true,
+	"group":         true,
+	"by":            true,
+	"having":        true,
+	"order":         true,
+	"limit":         true,
+	"offset":        true,
+	"as":            true,
+	KeywordIn:       true,
+	KeywordNot:      true,
+	KeywordLike:     true,
+	KeywordAnd:      true,
+	KeywordOr:       true,
+	KeywordIs:       true,
+	KeywordNULL:     true,
+	KeywordTrue:     true,
+	KeywordFalse:    true,
+	KeywordBetween:  true,
+	KeywordRegexp:   true,
+	KeywordRlike:    true,
+	KeywordILike:    true,
+	KeywordEscape:   false,
+	KeywordDiv:      false,
+	KeywordInterval: false,
+	KeywordCase:     false,
+	KeywordWhen:     false,
+	KeywordThen:     false,
+	KeywordElse:     false,
+	KeywordEnd:      false,
+	KeywordMissing:  false,
 }
 
 const (
-	KeywordIn   = "in"
-	KeywordNot  = "not"
-	KeywordLike = "like"
-	KeywordAnd  = "and"
-	KeywordOr   = "or"
-	KeywordIs   = "is"
-	KeywordNULL = "null"
+	KeywordIn       = "in"
+	KeywordNot      = "not"
+	KeywordLike     = "like"
+	KeywordAnd      = "and"
+	KeywordOr       = "or"
+	KeywordIs       = "is"
+	KeywordNULL     = "null"
+	KeywordTrue     = "true"
+	KeywordFalse    = "false"
+	KeywordBetween  = "between"
+	KeywordRegexp   = "regexp"
+	KeywordRlike    = "rlike"
+	KeywordILike    = "ilike"
+	KeywordEscape   = "escape"
+	KeywordDiv      = "div"
+	KeywordInterval = "interval"
+	KeywordCase     = "case"
+	KeywordWhen     = "when"
+	KeywordThen     = "then"
+	KeywordElse     = "else"
+	KeywordEnd      = "end"
+	KeywordMissing  = "missing"
 )
 
 const (
@@ -33,4 +76,9 @@ const (
 	OperatorGreaterEqual = ">="
 	OperatorNotEqual     = "!="
 	OperatorNotEqual2    = "<>"
+	OperatorRegexp       = "~"
+	OperatorIRegexp      = "~*"
+	OperatorNotRegexp    = "!~"
+	OperatorNotIRegexp   = "!~*"
+	OperatorConcat       = "||"
 )
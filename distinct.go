@@ -0,0 +1,175 @@
+package json_filter
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+
+	json "github.com/json-iterator/go"
+	"github.com/shopspring/decimal"
+)
+
+//sortedJSON map按key排序后编码，保证相同的值编码结果相同
+var sortedJSON = json.Config{SortMapKeys: true}.Froze()
+
+//encodeValues 将一组值编码为可比较的字符串，数值统一格式，使1和1.0的编码相同
+func encodeValues(values []interface{}) (string, error) {
+	normalized := make([]interface{}, len(values))
+	for i, v := range values {
+		normalized[i] = v
+		if isNumberValue(v) {
+			if d, err := toNumber(v); err == nil {
+				normalized[i] = numberValue(d)
+			}
+		}
+	}
+	bs, err := sortedJSON.Marshal(normalized)
+	if err != nil {
+		return "", err
+	}
+	return string(bs), nil
+}
+
+//hashString 64位哈希，对fnv的结果再做一次混合使各个位分布更均匀
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+//distinctSet 用于select distinct去重
+type distinctSet interface {
+	//Add 添加一个值，之前不存在时返回true
+	Add(string) bool
+}
+
+func newDistinctSet(approximate bool) distinctSet {
+	if approximate {
+		return newBloomFilter(bloomFilterBits, bloomFilterHashes)
+	}
+	return exactSet{}
+}
+
+type exactSet map[string]struct{}
+
+func (s exactSet) Add(v string) bool {
+	if _, ok := s[v]; ok {
+		return false
+	}
+	s[v] = struct{}{}
+	return true
+}
+
+const (
+	//bloomFilterBits 布隆过滤器的位数，占用8MB内存
+	bloomFilterBits = 1 << 26
+	//bloomFilterHashes 布隆过滤器的哈希函数个数
+	bloomFilterHashes = 5
+)
+
+//bloomFilter 布隆过滤器，内存固定，但有一定概率把不重复的数据误判为重复
+type bloomFilter struct {
+	bits   []uint64
+	hashes int
+}
+
+func newBloomFilter(size, hashes int) *bloomFilter {
+	return &bloomFilter{
+		bits:   make([]uint64, size/64),
+		hashes: hashes,
+	}
+}
+
+func (b *bloomFilter) Add(v string) bool {
+	h := hashString(v)
+	h1, h2 := h&0xffffffff, h>>32
+	size := uint64(len(b.bits) * 64)
+	added := false
+	for i := 0; i < b.hashes; i++ {
+		pos := (h1 + uint64(i)*h2) % size
+		word, mask := pos/64, uint64(1)<<(pos%64)
+		if b.bits[word]&mask == 0 {
+			b.bits[word] |= mask
+			added = true
+		}
+	}
+	return added
+}
+
+//hyperLogLogPrecision 寄存器个数为2^14，误差约0.8%
+const hyperLogLogPrecision = 14
+
+//hyperLogLog 用固定内存估算不重复值的个数
+type hyperLogLog struct {
+	registers []uint8
+}
+
+func newHyperLogLog() *hyperLogLog {
+	return &hyperLogLog{
+		registers: make([]uint8, 1<<hyperLogLogPrecision),
+	}
+}
+
+func (h *hyperLogLog) Add(v string) {
+	x := hashString(v)
+	index := x >> (64 - hyperLogLogPrecision)
+	w := x<<hyperLogLogPrecision | 1<<(hyperLogLogPrecision-1)
+	rank := uint8(bits.LeadingZeros64(w) + 1)
+	if rank > h.registers[index] {
+		h.registers[index] = rank
+	}
+}
+
+func (h *hyperLogLog) Count() int64 {
+	m := float64(len(h.registers))
+	var sum float64
+	var zeros int
+	for _, r := range h.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	//数量较少时使用线性计数
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return int64(math.Round(estimate))
+}
+
+//countDistinctAccumulator 计算count(distinct x)，忽略null
+type countDistinctAccumulator struct {
+	exact exactSet
+	hll   *hyperLogLog
+}
+
+func newCountDistinctAccumulator(approximate bool) *countDistinctAccumulator {
+	if approximate {
+		return &countDistinctAccumulator{hll: newHyperLogLog()}
+	}
+	return &countDistinctAccumulator{exact: exactSet{}}
+}
+
+//Add data为编码后的值
+func (a *countDistinctAccumulator) Add(data interface{}) {
+	v := data.(string)
+	if a.hll != nil {
+		a.hll.Add(v)
+	} else {
+		a.exact.Add(v)
+	}
+}
+
+func (a *countDistinctAccumulator) Result() interface{} {
+	if a.hll != nil {
+		return numberValue(decimal.NewFromInt(a.hll.Count()))
+	}
+	return numberValue(decimal.NewFromInt(int64(len(a.exact))))
+}
@@ -0,0 +1,39 @@
+package json_filter
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	for _, n := range []int{0, 1, 100, 10000, 200000} {
+		h := newHyperLogLog()
+		for i := 0; i < n; i++ {
+			h.Add(strconv.Itoa(i))
+			//重复的值不影响结果
+			h.Add(strconv.Itoa(i))
+		}
+		got := h.Count()
+		if diff := math.Abs(float64(got - int64(n))); diff > 0.03*float64(n)+1 {
+			t.Errorf("count of %d values = %d", n, got)
+		}
+	}
+}
+
+func TestBloomFilter(t *testing.T) {
+	set := newDistinctSet(true)
+	added := 0
+	for i := 0; i < 10000; i++ {
+		if set.Add(strconv.Itoa(i)) {
+			added++
+		}
+		if set.Add(strconv.Itoa(i)) {
+			t.Fatalf("%d added twice", i)
+		}
+	}
+	//误判的值不会被加入，但应该很少
+	if added < 9900 {
+		t.Errorf("added %d of 10000 distinct values", added)
+	}
+}
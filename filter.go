@@ -8,6 +8,7 @@ import (
 	"io"
 	"sort"
 	"strings"
+	"time"
 
 	json "github.com/json-iterator/go"
 )
@@ -17,54 +18,246 @@ type JSONFilter struct {
 	errWriter io.Writer
 	Line      []byte
 	fields    []string
+	items     []SelectItem
+	output    *rowWriter
 	checker   BoolNoder
+	grouper   *grouper
+	group     *group
+	having    BoolNoder
+	distinct  distinctSet
+	orderBy   []OrderByItem
+	sorter    *externalSorter
+	sortCfg   sortConfig
+	limit     int
+	offset    int
+	count     int
+	err       error
+	location  *time.Location
 }
 
 func (f *JSONFilter) Next() bool {
-	var err error
-	f.Line, err = f.reader.ReadBytes('\n')
-	if err != nil {
-		if !errors.Is(err, io.EOF) {
-			fmt.Fprintf(f.errWriter, "read line error: %v\n", err)
+	if f.err != nil {
+		return false
+	}
+	//达到limit后不再读取数据
+	if f.limit >= 0 && f.count >= f.limit {
+		f.Close()
+		return false
+	}
+	for ; f.offset > 0; f.offset-- {
+		if !f.nextRow() {
+			return false
 		}
+	}
+	if !f.nextRow() {
 		return false
 	}
-	f.Line = bytes.TrimSpace(f.Line)
-	ok, err := f.checker.Bool(f)
+	f.count++
+	return true
+}
+
+//nextRow 读取下一行结果
+func (f *JSONFilter) nextRow() bool {
+	if len(f.orderBy) == 0 {
+		return f.nextUnsorted()
+	}
+	if f.sorter == nil {
+		if err := f.sort(); err != nil {
+			if f.err == nil {
+				f.fail("sort error: %v", err)
+			}
+			return false
+		}
+	}
+	rec, ok, err := f.sorter.Next()
 	if err != nil {
-		fmt.Fprintf(f.errWriter, "check line error: %s\n", err.Error())
+		f.fail("sort error: %v", err)
 		return false
 	}
 	if !ok {
-		return f.Next()
+		f.Close()
+		return false
 	}
+	f.Line = rec.Data
 	return true
 }
 
-func (f *JSONFilter) Get(key string) (interface{}, error) {
-	return GetDataFromJSON(f.Line, key)
+//nextUnsorted 读取下一行未排序的结果，有分组时为下一个分组
+func (f *JSONFilter) nextUnsorted() bool {
+	for {
+		var ok bool
+		if f.grouper == nil {
+			ok = f.nextLine()
+		} else {
+			ok = f.nextGroup()
+		}
+		if !ok || f.distinct == nil {
+			return ok
+		}
+		key, err := f.distinctKey()
+		if err != nil {
+			fmt.Fprintf(f.errWriter, "distinct error: %v: %s\n", err, f.Line)
+			continue
+		}
+		if f.distinct.Add(key) {
+			return true
+		}
+	}
 }
 
-func (f *JSONFilter) GetData() ([]byte, error) {
-	if len(f.fields) == 1 && f.fields[0] == "*" {
-		return f.Line, nil
+//distinctKey select distinct时用于判断当前行是否重复
+func (f *JSONFilter) distinctKey() (string, error) {
+	values := make([]interface{}, len(f.items))
+	for i, item := range f.items {
+		if item.Name == "*" {
+			values[i] = string(f.Line)
+			continue
+		}
+		v, err := item.Expr.Interface(f)
+		if err != nil {
+			return "", err
+		}
+		values[i] = v
+	}
+	return encodeValues(values)
+}
+
+//nextGroup 第一次调用时读取所有数据并分组，之后每次返回一个分组的结果
+func (f *JSONFilter) nextGroup() bool {
+	if !f.grouper.done {
+		for f.nextLine() {
+			if err := f.grouper.Add(aliasGetter{f, f.items}, f, f.Line); err != nil {
+				//某一行计算出错时跳过该行，不加入任何分组
+				fmt.Fprintf(f.errWriter, "group error: %v: %s\n", err, f.Line)
+			}
+		}
+		if f.err != nil {
+			return false
+		}
+		f.grouper.Finish()
 	}
-	m := make(map[string]interface{})
-	for _, field := range f.fields {
-		if field == "*" {
+	for {
+		g, ok := f.grouper.Next()
+		f.group = g
+		if !ok {
+			return false
+		}
+		if f.having != nil {
+			ok, err := f.having.Bool(aliasGetter{g, f.items})
+			if err != nil {
+				//某个分组计算出错时跳过该分组
+				fmt.Fprintf(f.errWriter, "check group error: %v: %s\n", err, g.line)
+				continue
+			}
+			if !ok {
+				continue
+			}
+		}
+		bs, err := f.output.Write(g)
+		if err != nil {
+			fmt.Fprintf(f.errWriter, "group error: %v: %s\n", err, g.line)
 			continue
 		}
-		data, err := f.Get(field)
+		f.Line = bs
+		return true
+	}
+}
+
+//nextLine 读取下一条符合条件的数据
+func (f *JSONFilter) nextLine() bool {
+	for {
+		line, err := f.reader.ReadBytes('\n')
 		if err != nil {
-			return nil, err
+			if !errors.Is(err, io.EOF) {
+				f.fail("read line error: %v", err)
+			}
+			return false
+		}
+		f.Line = bytes.TrimSpace(line)
+		ok, err := f.checker.Bool(f)
+		if err != nil {
+			//某一行计算出错时跳过该行，不影响后面的数据
+			fmt.Fprintf(f.errWriter, "check line error: %v: %s\n", err, f.Line)
+			continue
+		}
+		if ok {
+			return true
 		}
-		m[field] = data
 	}
-	bs, err := json.Marshal(m)
-	if err != nil {
-		return nil, err
+}
+
+//sort 读取所有符合条件的数据并排序
+func (f *JSONFilter) sort() error {
+	f.sorter = newExternalSorter(f.orderBy, f.sortCfg)
+	for f.nextUnsorted() {
+		keys, err := sortKeys(f.orderBy, aliasGetter{f, f.items})
+		if err != nil {
+			//某一行计算排序的值出错时跳过该行
+			fmt.Fprintf(f.errWriter, "sort error: %v: %s\n", err, f.Line)
+			continue
+		}
+		if err := f.sorter.Add(sortRecord{Keys: keys, Data: f.Line}); err != nil {
+			return err
+		}
 	}
-	return bs, nil
+	if f.err != nil {
+		return f.err
+	}
+	return f.sorter.Sort()
+}
+
+func (f *JSONFilter) fail(format string, a ...interface{}) {
+	f.err = fmt.Errorf(format, a...)
+	fmt.Fprintln(f.errWriter, f.err.Error())
+	f.Close()
+}
+
+//Close 释放排序时产生的临时文件
+func (f *JSONFilter) Close() error {
+	if f.sorter == nil {
+		return nil
+	}
+	return f.sorter.Close()
+}
+
+//Get 从当前行取值，分组时从当前分组取值
+func (f *JSONFilter) Get(key string) (interface{}, error) {
+	if f.group != nil {
+		return f.group.Get(key)
+	}
+	return GetDataFromJSON(f.Line, key)
+}
+
+//Exists 判断当前行或当前分组中是否有该字段
+func (f *JSONFilter) Exists(key string) bool {
+	if f.group != nil {
+		return f.group.Exists(key)
+	}
+	return existsInJSON(f.Line, key)
+}
+
+//Location 计算时间时使用的时区
+func (f *JSONFilter) Location() *time.Location {
+	return f.location
+}
+
+//GetRaw 从当前行取出字段原始的json
+func (f *JSONFilter) GetRaw(key string) ([]byte, bool) {
+	if f.group != nil {
+		return f.group.GetRaw(key)
+	}
+	return getRawFromJSON(f.Line, key)
+}
+
+func (f *JSONFilter) GetData() ([]byte, error) {
+	//分组的结果在nextGroup中已经生成
+	if f.grouper != nil {
+		return f.Line, nil
+	}
+	if len(f.fields) == 1 && f.fields[0] == "*" {
+		return f.Line, nil
+	}
+	return f.output.Write(f)
 }
 
 func GetDataFromJSON(data []byte, key string) (interface{}, error) {
@@ -80,92 +273,185 @@ func GetDataFromJSON(data []byte, key string) (interface{}, error) {
 		sort.Sort(sort.StringSlice(keys))
 		return strings.Join(keys, ","), nil
 	}
-	if !strings.Contains(key, ".") {
-		return json.Get(data, key).GetInterface(), nil
+	if isSimplePath(key) {
+		keys := strings.Split(key, ".")
+		if len(keys) == 1 {
+			return exactJSON.Get(data, keys[0]).GetInterface(), nil
+		}
+		j := exactJSON.Get(data, keys[0])
+		for i := 1; i < len(keys); i++ {
+			j = j.Get(keys[i])
+		}
+		return j.GetInterface(), nil
 	}
-	keys := strings.Split(key, ".")
-	j := json.Get(data, keys[0])
-	for i := 1; i < len(keys); i++ {
-		j = j.Get(keys[i])
+	segments := parsePath(key)
+	if isPlainPath(segments) {
+		return exactJSON.Get(data, pathKeys(segments)...).GetInterface(), nil
 	}
-	return j.GetInterface(), nil
+	v, _ := walkPath(data, segments)
+	return v, nil
 }
 
-func GetFieldsAndChecker(sql string) ([]string, BoolNoder, error) {
-	fields := make([]string, 0)
-	tokens, err := Parse(sql)
+//existsInJSON 判断json中是否有该字段，字段的值为null时也存在
+func existsInJSON(data []byte, key string) bool {
+	_, ok := walkPath(data, parsePath(key))
+	return ok
+}
+
+//Statement 解析后的sql语句
+type Statement struct {
+	Distinct bool
+	Fields   []string
+	Items    []SelectItem
+	Checker  BoolNoder
+	GroupBy  []InterfaceNoder
+	//Having 对分组结果进行过滤
+	Having BoolNoder
+	//Aggregates select、having、order by中用到的所有聚合函数，不为空时需要分组
+	Aggregates []*NodeAggregate
+	OrderBy    []OrderByItem
+	//Limit 最多返回的行数，小于0时不限制
+	Limit  int
+	Offset int
+}
+
+//ParseStatement 解析sql语句，语法错误时返回*SyntaxError
+func ParseStatement(sql string) (*Statement, error) {
+	stmts, err := ParseStatements(sql)
 	if err != nil {
-		return nil, nil, fmt.Errorf("parse token error: %w", err)
+		return nil, err
 	}
-	if strings.ToLower(tokens[0].Str) != "select" {
-		return nil, nil, fmt.Errorf("sql syntax error[1]")
+	if len(stmts) != 1 {
+		return nil, fmt.Errorf("expected one statement, got %d", len(stmts))
 	}
-	foundExpression := false
-	foundFrom := false
-	expressionTokens := make([]*Token, 0)
-	for i := 1; i < len(tokens); i++ {
-		if strings.ToLower(tokens[i].Str) == "from" {
-			foundFrom = true
-			continue
-		}
-		if strings.ToLower(tokens[i].Str) == "t" {
-			if foundFrom && strings.ToLower(tokens[i-1].Str) == "from" {
-				if i-2 < 1 {
-					return nil, nil, fmt.Errorf("sql syntax error[2]")
-				}
-				for j := 1; j < i-1; j++ {
-					if tokens[j].Str == "," {
-						continue
-					}
-					fields = append(fields, tokens[j].Str)
-				}
-				if len(fields) == 0 {
-					return nil, nil, fmt.Errorf("sql syntax error[3]")
-				}
-				if i == len(tokens)-1 {
-					foundExpression = false
-					continue
-				}
-				if strings.ToLower(tokens[i+1].Str) != "where" {
-					return nil, nil, fmt.Errorf("sql syntax error[4]")
-				}
-				expressionTokens = tokens[i+2:]
-				if len(expressionTokens) == 0 {
-					return nil, nil, fmt.Errorf("sql syntax error[5]")
-				}
-				foundExpression = true
-			}
+	return stmts[0], nil
+}
+
+//ParseStatements 解析用;分隔的多条sql语句，语法错误时返回*SyntaxError
+func ParseStatements(sql string) ([]*Statement, error) {
+	tokens, err := Parse(sql)
+	if err != nil {
+		return nil, err
+	}
+	return newParser(sql, tokens).parseStatements()
+}
+
+//Grouped 是否需要分组
+func (stmt *Statement) Grouped() bool {
+	return len(stmt.GroupBy) > 0 || len(stmt.Aggregates) > 0 || stmt.Having != nil
+}
+
+//SelectItem select中的一项
+type SelectItem struct {
+	//Name 输出的字段名，有别名时为别名，否则为表达式的文本
+	Name string
+	//Alias 用as指定的别名
+	Alias string
+	Expr  InterfaceNoder
+}
+
+//aliasGetter 取值时优先按select中的别名计算表达式，用于group by、having、order by
+type aliasGetter struct {
+	getter Getter
+	items  []SelectItem
+}
+
+func (g aliasGetter) Get(key string) (interface{}, error) {
+	for _, item := range g.items {
+		if item.Alias != "" && item.Alias == key {
+			return item.Expr.Interface(g.getter)
 		}
 	}
-	if !foundExpression {
-		return fields, NodeTrue{}, nil
+	return g.getter.Get(key)
+}
+
+func (g aliasGetter) Exists(key string) bool {
+	for _, item := range g.items {
+		if item.Alias != "" && item.Alias == key {
+			return true
+		}
 	}
-	node, err := parseTokens(expressionTokens)
+	exists, _ := fieldExists(g.getter, key)
+	return exists
+}
+
+func (g aliasGetter) Location() *time.Location {
+	return getterLocation(g.getter)
+}
+
+func GetFieldsAndChecker(sql string) ([]string, BoolNoder, error) {
+	stmt, err := ParseStatement(sql)
 	if err != nil {
-		return nil, nil, fmt.Errorf("parse tokens to node error: %w", err)
+		return nil, nil, err
 	}
-	bNode, ok := node.(BoolNoder)
-	if !ok {
-		return nil, nil, fmt.Errorf("node is not BoolNoder")
-	}
-	return fields, bNode, nil
+	return stmt.Fields, stmt.Checker, nil
 }
 
 func NewJSONFilterWithConfig(cfg FilterConfig) (*JSONFilter, error) {
-	fields, checker, err := GetFieldsAndChecker(cfg.SQL)
+	stmt, err := ParseStatement(cfg.SQL)
+	if err != nil {
+		return nil, err
+	}
+	return newJSONFilter(stmt, cfg)
+}
+
+//newJSONFilter 用解析好的语句创建JSONFilter，cfg.SQL不再使用
+func newJSONFilter(stmt *Statement, cfg FilterConfig) (*JSONFilter, error) {
+	var gr *grouper
+	if stmt.Grouped() {
+		gr = newGrouper(stmt.GroupBy, stmt.Aggregates, cfg.ApproximateDistinct)
+	}
+	output, err := newRowWriter(stmt.Items, cfg.NestedOutput)
 	if err != nil {
 		return nil, err
 	}
+	var distinct distinctSet
+	if stmt.Distinct {
+		distinct = newDistinctSet(cfg.ApproximateDistinct)
+	}
 	return &JSONFilter{
+		grouper:   gr,
 		reader:    bufio.NewReader(cfg.Reader),
 		errWriter: cfg.ErrWriter,
-		fields:    fields,
-		checker:   checker,
+		fields:    stmt.Fields,
+		items:     stmt.Items,
+		output:    output,
+		checker:   stmt.Checker,
+		having:    stmt.Having,
+		distinct:  distinct,
+		orderBy:   stmt.OrderBy,
+		limit:     stmt.Limit,
+		offset:    stmt.Offset,
+		location:  cfg.Location,
+		sortCfg: sortConfig{
+			memoryLimit: cfg.SortMemoryLimit,
+			tempDir:     cfg.TempDir,
+			topN:        topN(stmt.Limit, stmt.Offset),
+		},
 	}, nil
 }
 
+//topN 有limit时排序只需要保留前limit+offset行
+func topN(limit, offset int) int {
+	if limit < 0 {
+		return 0
+	}
+	return limit + offset
+}
+
 type FilterConfig struct {
 	Reader    io.Reader
 	ErrWriter io.Writer
 	SQL       string
+	//SortMemoryLimit order by排序时最多在内存中缓存的数据字节数，超出后写入临时文件，为0时使用默认值
+	SortMemoryLimit int
+	//TempDir 排序临时文件所在目录，为空时使用系统默认临时目录
+	TempDir string
+	//ApproximateDistinct 为true时select distinct使用布隆过滤器去重，count(distinct x)使用HyperLogLog估算，
+	//内存占用固定，但结果可能有少量误差
+	ApproximateDistinct bool
+	//NestedOutput 为true时按字段名中的.输出嵌套的结构，如data.title输出为{"data":{"title":...}}
+	NestedOutput bool
+	//Location 解析没有时区的时间字符串、from_unixtime、date_trunc等使用的时区，为nil时使用本地时区
+	Location *time.Location
 }
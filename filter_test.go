@@ -0,0 +1,169 @@
+package json_filter
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+//runQuery 执行查询，返回输出的每一行和错误输出的每一行
+func runQuery(t *testing.T, cfg FilterConfig) ([]string, []string) {
+	t.Helper()
+	var errBuf bytes.Buffer
+	cfg.ErrWriter = &errBuf
+	f, err := NewJSONFilterWithConfig(cfg)
+	if err != nil {
+		t.Fatalf("%s: %v", cfg.SQL, err)
+	}
+	rows := make([]string, 0)
+	for f.Next() {
+		line, err := f.GetData()
+		if err != nil {
+			errBuf.WriteString(err.Error() + "\n")
+			continue
+		}
+		rows = append(rows, string(line))
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	errs := make([]string, 0)
+	if s := strings.TrimSpace(errBuf.String()); s != "" {
+		errs = strings.Split(s, "\n")
+	}
+	return rows, errs
+}
+
+//runSQL 对每行一个json的input执行sql
+func runSQL(t *testing.T, sql string, input string) ([]string, []string) {
+	t.Helper()
+	return runQuery(t, FilterConfig{Reader: strings.NewReader(input), SQL: sql})
+}
+
+//TestRowErrors 某一行计算出错时跳过该行并输出错误，不影响后面的数据
+func TestRowErrors(t *testing.T) {
+	input := `{"g":"x","lat":"1","a":4,"b":2}
+{"g":"x","lat":"abc","a":1,"b":0}
+{"g":"y","lat":"2","a":6,"b":3}
+{"g":"y","lat":3,"a":9,"b":3}
+`
+	cases := []struct {
+		sql  string
+		rows []string
+		errs []string
+	}{
+		{
+			"select g, sum(lat) as s, count(*) as c from t group by g",
+			[]string{`{"g":"x","s":1,"c":1}`, `{"g":"y","s":5,"c":2}`},
+			[]string{`group error: abc is not a number: can't convert abc to decimal: {"g":"x","lat":"abc","a":1,"b":0}`},
+		},
+		{
+			//出错的行不计入任何聚合函数
+			"select count(*) as c, sum(lat) as s from t",
+			[]string{`{"c":3,"s":6}`},
+			[]string{`group error: abc is not a number: can't convert abc to decimal: {"g":"x","lat":"abc","a":1,"b":0}`},
+		},
+		{
+			"select g, count(*) as c from t group by a / b",
+			[]string{`{"g":"x","c":2}`, `{"g":"y","c":1}`},
+			[]string{`group error: division by zero: {"g":"x","lat":"abc","a":1,"b":0}`},
+		},
+		{
+			"select g from t group by g having sum(a) / (sum(b) - 2) > 0",
+			[]string{`{"g":"y"}`},
+			[]string{`check group error: division by zero: {"g":"x","lat":"1","a":4,"b":2}`},
+		},
+		{
+			"select g, sum(a) / (count(*) - 2) as r from t group by g",
+			[]string{},
+			[]string{
+				`group error: division by zero: {"g":"x","lat":"1","a":4,"b":2}`,
+				`group error: division by zero: {"g":"y","lat":"2","a":6,"b":3}`,
+			},
+		},
+		{
+			"select a from t order by a / b desc",
+			[]string{`{"a":9}`, `{"a":4}`, `{"a":6}`},
+			[]string{`sort error: division by zero: {"g":"x","lat":"abc","a":1,"b":0}`},
+		},
+		{
+			"select distinct a / b as r from t",
+			[]string{`{"r":2}`, `{"r":3}`},
+			[]string{`distinct error: division by zero: {"g":"x","lat":"abc","a":1,"b":0}`},
+		},
+		{
+			"select a from t where a / b > 1",
+			[]string{`{"a":4}`, `{"a":6}`, `{"a":9}`},
+			[]string{`check line error: division by zero: {"g":"x","lat":"abc","a":1,"b":0}`},
+		},
+	}
+	for _, c := range cases {
+		rows, errs := runSQL(t, c.sql, input)
+		if !reflect.DeepEqual(rows, c.rows) {
+			t.Errorf("%s: rows %q, want %q", c.sql, rows, c.rows)
+		}
+		if !reflect.DeepEqual(errs, c.errs) {
+			t.Errorf("%s: errors %q, want %q", c.sql, errs, c.errs)
+		}
+	}
+}
+
+//countingReader 不断产生{"n":i}的输入，记录已经读取的行数
+type countingReader struct {
+	lines int
+	buf   []byte
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	for len(r.buf) < len(p) {
+		r.buf = append(r.buf, fmt.Sprintf(`{"n":%d}`+"\n", r.lines)...)
+		r.lines++
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func TestLimitOffset(t *testing.T) {
+	cases := []struct {
+		sql  string
+		rows []string
+	}{
+		{"select n from t limit 3", []string{`{"n":0}`, `{"n":1}`, `{"n":2}`}},
+		{"select n from t limit 2 offset 5", []string{`{"n":5}`, `{"n":6}`}},
+		{"select n from t where n % 10 = 0 limit 2 offset 1", []string{`{"n":10}`, `{"n":20}`}},
+		{"select n from t limit 0", []string{}},
+	}
+	for _, c := range cases {
+		r := &countingReader{}
+		rows, errs := runQuery(t, FilterConfig{Reader: r, SQL: c.sql})
+		if !reflect.DeepEqual(rows, c.rows) || len(errs) != 0 {
+			t.Errorf("%s: rows %q errors %q, want %q", c.sql, rows, errs, c.rows)
+		}
+		//取够limit行后不再读取输入，输入是无限的，能结束就说明提前停止了
+		if r.lines > 10000 {
+			t.Errorf("%s: read %d lines", c.sql, r.lines)
+		}
+	}
+}
+
+//TestOrderByLimit 排序后取前几行，只保留前limit+offset行
+func TestOrderByLimit(t *testing.T) {
+	input := "{\"a\":3}\n{\"a\":1}\n{\"a\":5}\n{\"a\":2}\n{\"a\":4}\n{\"a\":1,\"b\":1}\n"
+	cases := []struct {
+		sql  string
+		rows []string
+	}{
+		{"select * from t order by a limit 2", []string{`{"a":1}`, `{"a":1,"b":1}`}},
+		{"select a from t order by a desc limit 2 offset 1", []string{`{"a":4}`, `{"a":3}`}},
+		{"select a from t order by a limit 10 offset 4", []string{`{"a":4}`, `{"a":5}`}},
+	}
+	for _, c := range cases {
+		rows, errs := runSQL(t, c.sql, input)
+		if !reflect.DeepEqual(rows, c.rows) || len(errs) != 0 {
+			t.Errorf("%s: rows %q errors %q, want %q", c.sql, rows, errs, c.rows)
+		}
+	}
+}
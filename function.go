@@ -0,0 +1,229 @@
+package json_filter
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	_ InterfaceNoder = (*NodeFunction)(nil)
+	_ NumberNoder    = (*NodeFunction)(nil)
+	_ BoolNoder      = (*NodeFunction)(nil)
+)
+
+//functionCall 函数的实现，参数为计算后的值
+type functionCall func(args []interface{}) (interface{}, error)
+
+//zonedFunctionCall 需要时区的函数的实现，loc为FilterConfig中配置的时区
+type zonedFunctionCall func(args []interface{}, loc *time.Location) (interface{}, error)
+
+//function 标量函数的定义
+type function struct {
+	minArgs int
+	//maxArgs 小于0时不限制参数个数
+	maxArgs int
+	call    functionCall
+	//zonedCall 不为nil时代替call
+	zonedCall zonedFunctionCall
+	//bind 不为nil时在解析时调用，可以预先处理常量参数(如编译正则表达式)并返回实际的实现
+	bind func(args []InterfaceNoder) (functionCall, error)
+	//acceptNull 为false时任意参数为null则结果为null，不调用call
+	acceptNull bool
+}
+
+//functions 所有的标量函数，key为小写的函数名
+var functions = map[string]function{
+	"regexp_extract": {minArgs: 2, maxArgs: 3, bind: bindRegexpExtract},
+	"regexp_replace": {minArgs: 3, maxArgs: 3, bind: bindRegexpReplace},
+	"lower":          {minArgs: 1, maxArgs: 1, call: stringLower},
+	"upper":          {minArgs: 1, maxArgs: 1, call: stringUpper},
+	"length":         {minArgs: 1, maxArgs: 1, call: stringLength},
+	"substr":         {minArgs: 2, maxArgs: 3, call: stringSubstr},
+	"trim":           {minArgs: 1, maxArgs: 2, call: stringTrim},
+	"concat":         {minArgs: 1, maxArgs: -1, call: stringConcat, acceptNull: true},
+	"replace":        {minArgs: 3, maxArgs: 3, call: stringReplace},
+	"split_part":     {minArgs: 3, maxArgs: 3, call: stringSplitPart},
+	"starts_with":    {minArgs: 2, maxArgs: 2, call: stringStartsWith},
+	"ends_with":      {minArgs: 2, maxArgs: 2, call: stringEndsWith},
+	"contains":       {minArgs: 2, maxArgs: 2, call: stringContains},
+	"lpad":           {minArgs: 2, maxArgs: 3, call: stringLpad},
+	"rpad":           {minArgs: 2, maxArgs: 3, call: stringRpad},
+	"abs":            {minArgs: 1, maxArgs: 1, call: mathAbs},
+	"round":          {minArgs: 1, maxArgs: 2, call: mathRound},
+	"floor":          {minArgs: 1, maxArgs: 1, call: mathFloor},
+	"ceil":           {minArgs: 1, maxArgs: 1, call: mathCeil},
+	"pow":            {minArgs: 2, maxArgs: 2, call: mathPow},
+	"sqrt":           {minArgs: 1, maxArgs: 1, call: mathSqrt},
+	"log":            {minArgs: 1, maxArgs: 2, call: mathLog},
+	"greatest":       {minArgs: 1, maxArgs: -1, zonedCall: mathGreatest, acceptNull: true},
+	"least":          {minArgs: 1, maxArgs: -1, zonedCall: mathLeast, acceptNull: true},
+	"from_unixtime":  {minArgs: 1, maxArgs: 1, zonedCall: timeFromUnixtime},
+	"to_unixtime":    {minArgs: 1, maxArgs: 1, zonedCall: timeToUnixtime},
+	"parse_time":     {minArgs: 1, maxArgs: 2, zonedCall: timeParse},
+	"format_time":    {minArgs: 2, maxArgs: 2, zonedCall: timeFormat},
+	"date_trunc":     {minArgs: 2, maxArgs: 2, zonedCall: timeTrunc},
+	"now":            {minArgs: 0, maxArgs: 0, zonedCall: timeNow},
+}
+
+//NodeFunction 标量函数调用
+type NodeFunction struct {
+	Name       string
+	Args       []InterfaceNoder
+	call       functionCall
+	zonedCall  zonedFunctionCall
+	acceptNull bool
+}
+
+func (n NodeFunction) Type() NodeType {
+	return NodeTypeFunction
+}
+
+func (n NodeFunction) Children() []Noder {
+	children := make([]Noder, len(n.Args))
+	for i, arg := range n.Args {
+		children[i] = arg
+	}
+	return children
+}
+
+func (n NodeFunction) Interface(getter Getter) (interface{}, error) {
+	args := make([]interface{}, len(n.Args))
+	for i, arg := range n.Args {
+		v, err := arg.Interface(getter)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil && !n.acceptNull {
+			return nil, nil
+		}
+		args[i] = v
+	}
+	var v interface{}
+	var err error
+	if n.zonedCall != nil {
+		v, err = n.zonedCall(args, getterLocation(getter))
+	} else {
+		v, err = n.call(args)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.Name, err)
+	}
+	return v, nil
+}
+
+func (n NodeFunction) Number(getter Getter) (decimal.Decimal, error) {
+	data, err := n.Interface(getter)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return toNumber(data)
+}
+
+//Bool 函数的结果直接作为条件时必须是bool，为null时为false
+func (n NodeFunction) Bool(getter Getter) (bool, error) {
+	data, err := n.Interface(getter)
+	if err != nil {
+		return false, err
+	}
+	return resultBool(n.Name, data)
+}
+
+//toString 将字符串、数字、bool、时间转换为字符串
+func toString(data interface{}) (string, error) {
+	switch v := data.(type) {
+	case string:
+		return v, nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case Interval:
+		return v.String(), nil
+	case Number:
+		return string(v), nil
+	case bool:
+		if v {
+			return "true", nil
+		}
+		return "false", nil
+	}
+	if isNumberValue(data) {
+		d, err := toNumber(data)
+		if err != nil {
+			return "", err
+		}
+		return d.String(), nil
+	}
+	return "", fmt.Errorf("%v is not a string", data)
+}
+
+//toInt 将参数转换为整数
+func toInt(data interface{}) (int, error) {
+	d, err := toNumber(data)
+	if err != nil {
+		return 0, fmt.Errorf("%v is not a number", data)
+	}
+	if !d.Equal(d.Truncate(0)) {
+		return 0, fmt.Errorf("%v is not an integer", data)
+	}
+	return int(d.IntPart()), nil
+}
+
+//constantRegexp 编译作为常量的正则表达式参数
+func constantRegexp(arg InterfaceNoder) (*regexp.Regexp, error) {
+	s, ok := arg.(*NodeString)
+	if !ok {
+		return nil, fmt.Errorf("pattern must be a string literal")
+	}
+	return regexp.Compile(s.str)
+}
+
+//bindRegexpExtract regexp_extract(s, pattern[, group])，返回第group个分组匹配的内容，
+//group默认为0即整个匹配，没有匹配时返回null
+func bindRegexpExtract(args []InterfaceNoder) (functionCall, error) {
+	re, err := constantRegexp(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return func(args []interface{}) (interface{}, error) {
+		s, err := toString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		group := 0
+		if len(args) > 2 {
+			if group, err = toInt(args[2]); err != nil {
+				return nil, err
+			}
+		}
+		if group < 0 || group > re.NumSubexp() {
+			return nil, fmt.Errorf("group %d out of range", group)
+		}
+		m := re.FindStringSubmatchIndex(s)
+		if m == nil || m[2*group] < 0 {
+			return nil, nil
+		}
+		return s[m[2*group]:m[2*group+1]], nil
+	}, nil
+}
+
+//bindRegexpReplace regexp_replace(s, pattern, replacement)，替换所有匹配的内容，
+//replacement中可以用$1引用分组
+func bindRegexpReplace(args []InterfaceNoder) (functionCall, error) {
+	re, err := constantRegexp(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return func(args []interface{}) (interface{}, error) {
+		s, err := toString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		replacement, err := toString(args[2])
+		if err != nil {
+			return nil, err
+		}
+		return re.ReplaceAllString(s, replacement), nil
+	}, nil
+}
@@ -0,0 +1,165 @@
+package json_filter
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+//maxExactExponent 整数指数不超过该值时pow按精确的十进制计算
+const maxExactExponent = 1024
+
+//numberArg 将参数转换为数值
+func numberArg(data interface{}) (decimal.Decimal, error) {
+	d, err := toNumber(data)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("%v is not a number", data)
+	}
+	return d, nil
+}
+
+//floatValue 将float64的计算结果转换为Number，结果为NaN或无穷大时返回错误
+func floatValue(f float64) (interface{}, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("result out of range")
+	}
+	return numberValue(decimal.NewFromFloat(f)), nil
+}
+
+func mathAbs(args []interface{}) (interface{}, error) {
+	d, err := numberArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return numberValue(d.Abs()), nil
+}
+
+//mathRound round(x[, n])，四舍五入保留n位小数，n默认为0，可以为负数
+func mathRound(args []interface{}) (interface{}, error) {
+	d, err := numberArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	places := 0
+	if len(args) > 1 {
+		if places, err = toInt(args[1]); err != nil {
+			return nil, err
+		}
+	}
+	return numberValue(d.Round(int32(places))), nil
+}
+
+func mathFloor(args []interface{}) (interface{}, error) {
+	d, err := numberArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return numberValue(d.Floor()), nil
+}
+
+func mathCeil(args []interface{}) (interface{}, error) {
+	d, err := numberArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return numberValue(d.Ceil()), nil
+}
+
+//mathPow pow(x, y)，y为整数时精确计算，否则按浮点数计算
+func mathPow(args []interface{}) (interface{}, error) {
+	x, err := numberArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	y, err := numberArg(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if y.Equal(y.Truncate(0)) && y.Abs().LessThanOrEqual(decimal.New(maxExactExponent, 0)) {
+		n := y.IntPart()
+		if n < 0 && x.IsZero() {
+			return nil, errDivisionByZero
+		}
+		result := decimal.New(1, 0)
+		base := x
+		for e := n; e != 0; e /= 2 {
+			if e%2 != 0 {
+				result = result.Mul(base)
+			}
+			base = base.Mul(base)
+		}
+		if n < 0 {
+			result = decimal.New(1, 0).Div(result)
+		}
+		return numberValue(result), nil
+	}
+	fx, _ := x.Float64()
+	fy, _ := y.Float64()
+	return floatValue(math.Pow(fx, fy))
+}
+
+func mathSqrt(args []interface{}) (interface{}, error) {
+	d, err := numberArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if d.IsNegative() {
+		return nil, fmt.Errorf("square root of negative number %s", d)
+	}
+	f, _ := d.Float64()
+	return floatValue(math.Sqrt(f))
+}
+
+//mathLog log(x)为自然对数，log(b, x)为以b为底的对数
+func mathLog(args []interface{}) (interface{}, error) {
+	values := make([]float64, len(args))
+	for i, arg := range args {
+		d, err := numberArg(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !d.IsPositive() {
+			return nil, fmt.Errorf("logarithm of non-positive number %s", d)
+		}
+		values[i], _ = d.Float64()
+	}
+	if len(values) == 1 {
+		return floatValue(math.Log(values[0]))
+	}
+	if values[0] == 1 {
+		return nil, fmt.Errorf("logarithm base cannot be 1")
+	}
+	return floatValue(math.Log(values[1]) / math.Log(values[0]))
+}
+
+//extremum 返回参数中最大(sign为1)或最小(sign为-1)的值，忽略null，都为null时返回null
+func extremum(args []interface{}, sign int, loc *time.Location) (interface{}, error) {
+	var result interface{}
+	for _, arg := range args {
+		if arg == nil {
+			continue
+		}
+		if result == nil {
+			result = arg
+			continue
+		}
+		c, ok := compareOrdered(arg, result, loc)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare %v with %v", arg, result)
+		}
+		if c*sign > 0 {
+			result = arg
+		}
+	}
+	return result, nil
+}
+
+func mathGreatest(args []interface{}, loc *time.Location) (interface{}, error) {
+	return extremum(args, 1, loc)
+}
+
+func mathLeast(args []interface{}, loc *time.Location) (interface{}, error) {
+	return extremum(args, -1, loc)
+}
@@ -0,0 +1,43 @@
+package json_filter
+
+import (
+	"testing"
+)
+
+func TestMathFunctions(t *testing.T) {
+	getter := mapGetter{"n": Number("-7.5"), "i": Number("1234"), "z": Number("0"), "x": nil}
+	checkValues(t, getter, []valueCase{
+		{"abs(n)", "7.5"},
+		{"abs('-3')", "3"},
+		{"round(n)", "-8"},
+		{"round(2.345, 2)", "2.35"},
+		{"round(-2.5)", "-3"},
+		{"round(i, -2)", "1200"},
+		{"floor(n)", "-8"},
+		{"ceil(n)", "-7"},
+		{"pow(2, 10)", "1024"},
+		{"pow(2, -1)", "0.5"},
+		{"pow(4, 0.5)", "2"},
+		{"sqrt(16)", "4"},
+		{"log(2, 8)", "3"},
+		{"round(log(100), 6)", "4.60517"},
+		{"greatest(1, 5, x, 3)", "5"},
+		{"least(1, 5, x, 3)", "1"},
+		{"greatest('a', 'b')", "b"},
+		{"abs(x)", "<nil>"},
+		{"-7 div 2", "-3"},
+		{"7 div -2", "-3"},
+		{"7 % 3", "1"},
+		{"-7 % 3", "-1"},
+		{"i / 8", "154.25"},
+	})
+	for _, expr := range []string{"i / z", "i % z", "i div z", "sqrt(n)", "log(z)", "log(n)"} {
+		stmt, err := ParseStatement("select " + expr + " from t")
+		if err != nil {
+			t.Fatalf("%s: %v", expr, err)
+		}
+		if _, err := stmt.Items[0].Expr.Interface(getter); err == nil {
+			t.Errorf("%s: want an error", expr)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package json_filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+)
+
+//stringArgs 将参数都转换为字符串
+func stringArgs(args []interface{}) ([]string, error) {
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		s, err := toString(arg)
+		if err != nil {
+			return nil, err
+		}
+		strs[i] = s
+	}
+	return strs, nil
+}
+
+func stringLower(args []interface{}) (interface{}, error) {
+	s, err := toString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return strings.ToLower(s), nil
+}
+
+func stringUpper(args []interface{}) (interface{}, error) {
+	s, err := toString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return strings.ToUpper(s), nil
+}
+
+//stringLength 字符串的字符数
+func stringLength(args []interface{}) (interface{}, error) {
+	s, err := toString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return numberValue(decimal.NewFromInt(int64(utf8.RuneCountInString(s)))), nil
+}
+
+//stringSubstr substr(s, start[, length])，start从1开始，为负数时从末尾开始计算
+func stringSubstr(args []interface{}) (interface{}, error) {
+	s, err := toString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	start, err := toInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+	runes := []rune(s)
+	switch {
+	case start > 0:
+		start--
+	case start < 0:
+		start += len(runes)
+		if start < 0 {
+			start = 0
+		}
+	}
+	if start > len(runes) {
+		start = len(runes)
+	}
+	end := len(runes)
+	if len(args) > 2 {
+		length, err := toInt(args[2])
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, fmt.Errorf("negative length %d", length)
+		}
+		if start+length < end {
+			end = start + length
+		}
+	}
+	return string(runes[start:end]), nil
+}
+
+//stringTrim trim(s[, chars])，去掉两端的空白或chars中的字符
+func stringTrim(args []interface{}) (interface{}, error) {
+	strs, err := stringArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	if len(strs) > 1 {
+		return strings.Trim(strs[0], strs[1]), nil
+	}
+	return strings.TrimSpace(strs[0]), nil
+}
+
+//stringConcat concat(a, b, ...)，忽略为null的参数
+func stringConcat(args []interface{}) (interface{}, error) {
+	var b strings.Builder
+	for _, arg := range args {
+		if arg == nil {
+			continue
+		}
+		s, err := toString(arg)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(s)
+	}
+	return b.String(), nil
+}
+
+//stringReplace replace(s, from, to)，替换所有的from
+func stringReplace(args []interface{}) (interface{}, error) {
+	strs, err := stringArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	return strings.ReplaceAll(strs[0], strs[1], strs[2]), nil
+}
+
+//stringSplitPart split_part(s, delimiter, n)，返回按delimiter拆分后的第n部分，
+//n从1开始，为负数时从末尾开始计算，超出范围时返回空字符串
+func stringSplitPart(args []interface{}) (interface{}, error) {
+	s, err := toString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	delimiter, err := toString(args[1])
+	if err != nil {
+		return nil, err
+	}
+	n, err := toInt(args[2])
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("field position must not be zero")
+	}
+	parts := []string{s}
+	if delimiter != "" {
+		parts = strings.Split(s, delimiter)
+	}
+	if n < 0 {
+		n += len(parts) + 1
+	}
+	if n < 1 || n > len(parts) {
+		return "", nil
+	}
+	return parts[n-1], nil
+}
+
+func stringStartsWith(args []interface{}) (interface{}, error) {
+	strs, err := stringArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	return strings.HasPrefix(strs[0], strs[1]), nil
+}
+
+func stringEndsWith(args []interface{}) (interface{}, error) {
+	strs, err := stringArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	return strings.HasSuffix(strs[0], strs[1]), nil
+}
+
+func stringContains(args []interface{}) (interface{}, error) {
+	strs, err := stringArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Contains(strs[0], strs[1]), nil
+}
+
+func stringLpad(args []interface{}) (interface{}, error) {
+	return pad(args, true)
+}
+
+func stringRpad(args []interface{}) (interface{}, error) {
+	return pad(args, false)
+}
+
+//pad lpad(s, length[, fill])、rpad(s, length[, fill])，用fill(默认为空格)把s补齐到length个字符，
+//s超过length时截断
+func pad(args []interface{}, left bool) (interface{}, error) {
+	s, err := toString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	length, err := toInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("negative length %d", length)
+	}
+	fill := " "
+	if len(args) > 2 {
+		if fill, err = toString(args[2]); err != nil {
+			return nil, err
+		}
+	}
+	runes := []rune(s)
+	if len(runes) >= length {
+		return string(runes[:length]), nil
+	}
+	fillRunes := []rune(fill)
+	if len(fillRunes) == 0 {
+		return s, nil
+	}
+	padding := make([]rune, length-len(runes))
+	for i := range padding {
+		padding[i] = fillRunes[i%len(fillRunes)]
+	}
+	if left {
+		return string(padding) + s, nil
+	}
+	return s + string(padding), nil
+}
@@ -0,0 +1,66 @@
+package json_filter
+
+import (
+	"fmt"
+	"testing"
+)
+
+//valueCase 表达式和期望的结果，结果用fmt.Sprint格式化
+type valueCase struct {
+	expr string
+	want string
+}
+
+//checkValues 对getter计算每个表达式
+func checkValues(t *testing.T, getter Getter, cases []valueCase) {
+	t.Helper()
+	for _, c := range cases {
+		if got := fmt.Sprint(evalValue(t, c.expr, getter)); got != c.want {
+			t.Errorf("%s = %s, want %s", c.expr, got, c.want)
+		}
+	}
+}
+
+func TestStringFunctions(t *testing.T) {
+	getter := mapGetter{"s": "Hello, 世界", "sp": "  hi  ", "csv": "a,b,,c", "x": nil}
+	checkValues(t, getter, []valueCase{
+		{"lower(s)", "hello, 世界"},
+		{"upper(s)", "HELLO, 世界"},
+		{"length(s)", "9"},
+		{"length(123)", "3"},
+		{"substr(s, 8)", "世界"},
+		{"substr(s, 1, 5)", "Hello"},
+		{"substr(s, -2)", "世界"},
+		{"substr(s, -2, 1)", "世"},
+		{"trim(sp)", "hi"},
+		{"trim('xxhixx', 'x')", "hi"},
+		{"concat(s, '!', x, 1)", "Hello, 世界!1"},
+		{"s || '!'", "Hello, 世界!"},
+		{"s || x", "<nil>"},
+		{"replace(csv, ',', ';')", "a;b;;c"},
+		{"split_part(csv, ',', 2)", "b"},
+		{"split_part(csv, ',', 3)", ""},
+		{"split_part(csv, ',', -1)", "c"},
+		{"split_part(csv, ',', 9)", ""},
+		{"lpad('7', 3, '0')", "007"},
+		{"rpad('ab', 5, '.')", "ab..."},
+		{"lpad('abcdef', 3)", "abc"},
+		{"rpad(s, 3)", "Hel"},
+		{"upper(1)", "1"},
+		{"lower(x)", "<nil>"},
+		{"length(x)", "<nil>"},
+	})
+	checkBools(t, getter, []boolCase{
+		{"starts_with(s, 'Hell')", true},
+		{"starts_with(s, 'hell')", false},
+		{"ends_with(s, '世界')", true},
+		{"contains(csv, ',,')", true},
+		{"contains(csv, ';')", false},
+		{"contains(x, 'a')", false},
+	})
+	for _, sql := range []string{"select upper(s, 1) from t", "select nosuch(s) from t"} {
+		if _, err := ParseStatement(sql); err == nil {
+			t.Errorf("%s: want an error", sql)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package json_filter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+func timeFromUnixtime(args []interface{}, loc *time.Location) (interface{}, error) {
+	d, err := numberArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return unixTime(d, loc), nil
+}
+
+func timeToUnixtime(args []interface{}, loc *time.Location) (interface{}, error) {
+	t, err := toTime(args[0], loc)
+	if err != nil {
+		return nil, err
+	}
+	return numberValue(unixSeconds(t)), nil
+}
+
+//timeParse parse_time(s[, layout])，layout使用Go的时间格式，如'2006-01-02 15:04:05'，
+//省略时自动识别常见的格式，没有时区的按配置的时区解析
+func timeParse(args []interface{}, loc *time.Location) (interface{}, error) {
+	s, err := toString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(args) < 2 {
+		if t, ok := parseTimeString(s, loc); ok {
+			return t, nil
+		}
+		return nil, fmt.Errorf("cannot parse time '%s'", s)
+	}
+	layout, err := toString(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return time.ParseInLocation(layout, s, loc)
+}
+
+//timeFormat format_time(t, layout)，按Go的时间格式在配置的时区中格式化
+func timeFormat(args []interface{}, loc *time.Location) (interface{}, error) {
+	t, err := toTime(args[0], loc)
+	if err != nil {
+		return nil, err
+	}
+	layout, err := toString(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return t.In(loc).Format(layout), nil
+}
+
+//timeTrunc date_trunc(unit, t)，在配置的时区中截断到指定的单位，week从周一开始
+func timeTrunc(args []interface{}, loc *time.Location) (interface{}, error) {
+	unit, err := toString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	t, err := toTime(args[1], loc)
+	if err != nil {
+		return nil, err
+	}
+	t = t.In(loc)
+	year, month, day := t.Date()
+	hour, min, sec := t.Clock()
+	switch strings.ToLower(unit) {
+	case "second":
+		return time.Date(year, month, day, hour, min, sec, 0, loc), nil
+	case "minute":
+		return time.Date(year, month, day, hour, min, 0, 0, loc), nil
+	case "hour":
+		return time.Date(year, month, day, hour, 0, 0, 0, loc), nil
+	case "day":
+		return time.Date(year, month, day, 0, 0, 0, 0, loc), nil
+	case "week":
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day-offset, 0, 0, 0, 0, loc), nil
+	case "month":
+		return time.Date(year, month, 1, 0, 0, 0, 0, loc), nil
+	case "quarter":
+		return time.Date(year, month-(month-1)%3, 1, 0, 0, 0, 0, loc), nil
+	case "year":
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, loc), nil
+	}
+	return nil, fmt.Errorf("unknown unit %s", unit)
+}
+
+//timeNow now()，当前时间
+func timeNow(args []interface{}, loc *time.Location) (interface{}, error) {
+	return time.Now().In(loc), nil
+}
@@ -0,0 +1,76 @@
+package json_filter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+//zonedGetter 指定时区的mapGetter
+type zonedGetter struct {
+	mapGetter
+	loc *time.Location
+}
+
+func (g zonedGetter) Location() *time.Location {
+	return g.loc
+}
+
+func TestTimeFunctions(t *testing.T) {
+	getter := zonedGetter{
+		mapGetter{"ts": Number("1700000000"), "tstr": "2024-03-15 10:20:30", "x": nil},
+		time.UTC,
+	}
+	checkValues(t, getter, []valueCase{
+		{"to_unixtime('2024-01-01T00:00:00Z')", "1704067200"},
+		{"to_unixtime(from_unixtime(1.5))", "1.5"},
+		{"format_time(from_unixtime(ts), '2006-01-02 15:04')", "2023-11-14 22:13"},
+		{"format_time(ts, '2006-01-02 15:04')", "2023-11-14 22:13"},
+		{"format_time(tstr, '15:04:05')", "10:20:30"},
+		{"format_time(date_trunc('hour', tstr), '2006-01-02 15:04:05')", "2024-03-15 10:00:00"},
+		{"format_time(date_trunc('day', ts), '2006-01-02 15:04:05')", "2023-11-14 00:00:00"},
+		{"format_time(date_trunc('week', '2024-03-15'), '2006-01-02')", "2024-03-11"},
+		{"format_time(date_trunc('month', tstr), '2006-01-02')", "2024-03-01"},
+		{"format_time(date_trunc('quarter', tstr), '2006-01-02')", "2024-01-01"},
+		{"format_time(date_trunc('year', tstr), '2006-01-02')", "2024-01-01"},
+		{"format_time(parse_time('15/03/2024 10:20', '02/01/2006 15:04'), '2006-01-02T15:04')", "2024-03-15T10:20"},
+		{"to_unixtime(parse_time('2024-03-15'))", "1710460800"},
+		{"format_time(from_unixtime(ts) + interval '1 hour 30 minutes', '15:04')", "23:43"},
+		{"format_time(from_unixtime(ts) - interval '1h', '15:04')", "21:13"},
+		{"from_unixtime(ts) - from_unixtime(ts - 90)", "1m30s"},
+		{"interval '2 days'", "48h0m0s"},
+		{"from_unixtime(x)", "<nil>"},
+	})
+	checkBools(t, getter, []boolCase{
+		{"ts > '2023-11-14 22:00:00'", true},
+		{"ts < '2023-11-14'", false},
+		{"from_unixtime(ts) = '2023-11-14T22:13:20Z'", true},
+		{"tstr > ts", true},
+		{"tstr between '2024-03-15' and '2024-03-16'", true},
+		{"from_unixtime(ts) > now() - interval '5 minutes'", false},
+		{"now() > ts", true},
+	})
+
+	//没有时区的时间按指定的时区解析
+	getter.loc = time.FixedZone("CST", 8*3600)
+	checkValues(t, getter, []valueCase{
+		{"to_unixtime(tstr)", "1710469230"},
+		{"to_unixtime('2024-03-15T10:20:30Z')", "1710498030"},
+		{"format_time(ts, '2006-01-02 15:04')", "2023-11-15 06:13"},
+		{"format_time(date_trunc('day', ts), '2006-01-02 15:04')", "2023-11-15 00:00"},
+	})
+}
+
+//TestTimeOutput 时间输出为RFC3339格式的字符串
+func TestTimeOutput(t *testing.T) {
+	rows, errs := runQuery(t, FilterConfig{
+		Reader:   strings.NewReader(`{"ts":1700000000}` + "\n"),
+		SQL:      "select from_unixtime(ts) as t, date_trunc('hour', ts) as h from t",
+		Location: time.FixedZone("CST", 8*3600),
+	})
+	want := []string{`{"t":"2023-11-15T06:13:20+08:00","h":"2023-11-15T06:00:00+08:00"}`}
+	if !reflect.DeepEqual(rows, want) || len(errs) != 0 {
+		t.Errorf("rows %q errors %q, want %q", rows, errs, want)
+	}
+}
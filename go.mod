@@ -3,7 +3,7 @@ module github.com/jiazhoulvke/json_filter
 go 1.14
 
 require (
-	github.com/json-iterator/go v1.1.10
+	github.com/json-iterator/go v1.1.12
 	github.com/shopspring/decimal v1.2.0
 	github.com/spf13/pflag v1.0.5
 )
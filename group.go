@@ -0,0 +1,121 @@
+package json_filter
+
+//group 一个分组，保存组内第一行数据和各聚合函数的累加器
+type group struct {
+	line []byte
+	accs map[string]accumulator
+}
+
+//Get 优先返回聚合函数的结果，否则从组内第一行数据中取值
+func (g *group) Get(key string) (interface{}, error) {
+	if acc, ok := g.accs[key]; ok {
+		return acc.Result(), nil
+	}
+	return GetDataFromJSON(g.line, key)
+}
+
+//Exists 聚合函数的结果总是存在，其他字段判断组内第一行数据中是否存在
+func (g *group) Exists(key string) bool {
+	if _, ok := g.accs[key]; ok {
+		return true
+	}
+	return existsInJSON(g.line, key)
+}
+
+//GetRaw 聚合函数没有原始json，其他字段从组内第一行数据中取
+func (g *group) GetRaw(key string) ([]byte, bool) {
+	if _, ok := g.accs[key]; ok {
+		return nil, false
+	}
+	return getRawFromJSON(g.line, key)
+}
+
+//grouper 按group by的表达式对数据分组
+type grouper struct {
+	keys   []InterfaceNoder
+	aggs   []*NodeAggregate
+	groups map[string]*group
+	order  []*group
+	index  int
+	done   bool
+	//approximate count(distinct x)是否使用近似算法
+	approximate bool
+}
+
+func newGrouper(keys []InterfaceNoder, aggs []*NodeAggregate, approximate bool) *grouper {
+	return &grouper{
+		approximate: approximate,
+		keys:        keys,
+		aggs:        uniqueAggregates(aggs),
+		groups:      make(map[string]*group),
+		order:       make([]*group, 0),
+	}
+}
+
+func (gr *grouper) newGroup(line []byte) *group {
+	g := &group{
+		line: line,
+		accs: make(map[string]accumulator, len(gr.aggs)),
+	}
+	for _, agg := range gr.aggs {
+		g.accs[agg.Key] = agg.newAccumulator(gr.approximate)
+	}
+	gr.order = append(gr.order, g)
+	return g
+}
+
+//Add 将一行数据加入所属的分组，keyGetter用于计算分组的表达式，可以使用select中的别名，
+//出错时该行不加入任何分组
+func (gr *grouper) Add(keyGetter Getter, getter Getter, line []byte) error {
+	args := make([]interface{}, len(gr.aggs))
+	for i, agg := range gr.aggs {
+		v, err := agg.argValue(getter)
+		if err != nil {
+			return err
+		}
+		args[i] = v
+	}
+	values := make([]interface{}, len(gr.keys))
+	for i, key := range gr.keys {
+		v, err := key.Interface(keyGetter)
+		if err != nil {
+			return err
+		}
+		values[i] = v
+	}
+	k, err := encodeValues(values)
+	if err != nil {
+		return err
+	}
+	g, ok := gr.groups[k]
+	if !ok {
+		g = gr.newGroup(line)
+		gr.groups[k] = g
+	}
+	for i, agg := range gr.aggs {
+		if args[i] != nil {
+			g.accs[agg.Key].Add(args[i])
+		}
+	}
+	return nil
+}
+
+//Finish 结束分组，没有group by时即使没有数据也返回一个分组
+func (gr *grouper) Finish() {
+	gr.done = true
+	gr.groups = nil
+	if len(gr.keys) == 0 && len(gr.order) == 0 {
+		gr.newGroup(nil)
+	}
+}
+
+//Next 按分组出现的顺序返回下一个分组
+func (gr *grouper) Next() (*group, bool) {
+	if gr.index >= len(gr.order) {
+		return nil, false
+	}
+	g := gr.order[gr.index]
+	gr.order[gr.index] = nil
+	gr.index++
+	return g, true
+}
@@ -0,0 +1,112 @@
+package json_filter
+
+import (
+	"reflect"
+	"testing"
+)
+
+const groupInput = `{"level":"info","data":{"latency":10},"msg":"a"}
+{"level":"error","data":{"latency":30},"msg":"b"}
+{"level":"info","data":{"latency":20},"msg":"a"}
+{"level":"info","msg":"c"}
+{"level":"error","data":{"latency":"5.5"},"msg":"b"}
+{"data":{"latency":1}}
+`
+
+func TestGroupBy(t *testing.T) {
+	cases := []struct {
+		sql  string
+		rows []string
+	}{
+		{
+			"select level, count(*), avg(data.latency) from t group by level",
+			[]string{
+				`{"level":"info","count(*)":3,"avg(data.latency)":15}`,
+				`{"level":"error","count(*)":2,"avg(data.latency)":17.75}`,
+				`{"level":null,"count(*)":1,"avg(data.latency)":1}`,
+			},
+		},
+		{
+			"select level, count(data.latency) as n, sum(data.latency) as s, min(msg) as lo from t group by level",
+			[]string{
+				`{"level":"info","n":2,"s":30,"lo":"a"}`,
+				`{"level":"error","n":2,"s":35.5,"lo":"b"}`,
+				`{"level":null,"n":1,"s":1,"lo":null}`,
+			},
+		},
+		{
+			"select lower(level) as l, count(*) as c from t group by l",
+			[]string{`{"l":"info","c":3}`, `{"l":"error","c":2}`, `{"l":null,"c":1}`},
+		},
+		{
+			"select data.latency, count(*) as c from t group by data.latency",
+			[]string{
+				`{"data.latency":10,"c":1}`, `{"data.latency":30,"c":1}`, `{"data.latency":20,"c":1}`,
+				`{"data.latency":null,"c":1}`, `{"data.latency":"5.5","c":1}`, `{"data.latency":1,"c":1}`,
+			},
+		},
+		{
+			"select count(*) as c, count(distinct msg) as m, max(data.latency) as hi from t where level = 'info'",
+			[]string{`{"c":3,"m":2,"hi":20}`},
+		},
+		//没有group by时即使没有数据也输出一行
+		{
+			"select count(*) as c, sum(x) as s from t where level = 'none'",
+			[]string{`{"c":0,"s":null}`},
+		},
+		{
+			"select level, count(*) as c from t where level = 'none' group by level",
+			[]string{},
+		},
+	}
+	for _, c := range cases {
+		rows, errs := runSQL(t, c.sql, groupInput)
+		if !reflect.DeepEqual(rows, c.rows) || len(errs) != 0 {
+			t.Errorf("%s: rows %q errors %q, want %q", c.sql, rows, errs, c.rows)
+		}
+	}
+}
+
+func TestHaving(t *testing.T) {
+	cases := []struct {
+		sql  string
+		rows []string
+	}{
+		{
+			"select level, count(*) as c from t group by level having count(*) > 1",
+			[]string{`{"level":"info","c":3}`, `{"level":"error","c":2}`},
+		},
+		//having中可以使用select中的别名
+		{
+			"select level, count(*) as c from t group by level having c > 2",
+			[]string{`{"level":"info","c":3}`},
+		},
+		{
+			"select level, avg(data.latency) as a from t group by level having avg(data.latency) >= 15 and level is not null",
+			[]string{`{"level":"info","a":15}`, `{"level":"error","a":17.75}`},
+		},
+		//having中的聚合函数可以不在select中
+		{
+			"select level from t group by level having sum(data.latency) = 30 or min(msg) = 'b'",
+			[]string{`{"level":"info"}`, `{"level":"error"}`},
+		},
+		{
+			"select level from t group by level having count(distinct msg) = 2",
+			[]string{`{"level":"info"}`},
+		},
+		{
+			"select level from t group by level having not count(*) < 2 order by level",
+			[]string{`{"level":"error"}`, `{"level":"info"}`},
+		},
+		{
+			"select count(*) as c from t having c > 100",
+			[]string{},
+		},
+	}
+	for _, c := range cases {
+		rows, errs := runSQL(t, c.sql, groupInput)
+		if !reflect.DeepEqual(rows, c.rows) || len(errs) != 0 {
+			t.Errorf("%s: rows %q errors %q, want %q", c.sql, rows, errs, c.rows)
+		}
+	}
+}
@@ -1,25 +1,38 @@
 package json_filter
 
-import "unicode/utf8"
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+//likeOptions like的匹配选项
+type likeOptions struct {
+	//escape 转义字符，为0时没有转义字符
+	escape rune
+	//ignoreCase 是否忽略大小写(ilike)
+	ignoreCase bool
+}
 
 //match https://github.com/golang/go/blob/master/src/path/filepath/match.go
-func match(pattern string, str string) bool {
+func match(pattern string, str string, opts likeOptions) bool {
 Pattern:
 	for len(pattern) > 0 {
 		var percentSymbol bool
 		var chunk string
-		percentSymbol, chunk, pattern = scanChunk(pattern)
+		percentSymbol, chunk, pattern = scanChunk(pattern, opts)
 		if percentSymbol && chunk == "" {
 			return true
 		}
-		t, ok := matchChunk(chunk, str)
+		t, ok := matchChunk(chunk, str, opts)
 		if ok && (len(t) == 0 || len(pattern) > 0) {
 			str = t
 			continue
 		}
 		if percentSymbol {
-			for i := 0; i < len(str); i++ {
-				t, ok := matchChunk(chunk, str[i+1:])
+			for i := 0; i < len(str); {
+				_, n := utf8.DecodeRuneInString(str[i:])
+				i += n
+				t, ok := matchChunk(chunk, str[i:], opts)
 				if ok {
 					if len(pattern) == 0 && len(t) > 0 {
 						continue
@@ -34,38 +47,69 @@ Pattern:
 	return len(str) == 0
 }
 
-func scanChunk(pattern string) (percentSymbol bool, chunk, rest string) {
+func scanChunk(pattern string, opts likeOptions) (percentSymbol bool, chunk, rest string) {
 	for len(pattern) > 0 && pattern[0] == '%' {
 		pattern = pattern[1:]
 		percentSymbol = true
 	}
 	var i int
 Scan:
-	for i = 0; i < len(pattern); i++ {
-		if pattern[i] == '%' {
+	for i = 0; i < len(pattern); {
+		r, n := utf8.DecodeRuneInString(pattern[i:])
+		switch {
+		case opts.escape != 0 && r == opts.escape:
+			//跳过转义字符和被转义的字符
+			i += n
+			if i < len(pattern) {
+				_, n = utf8.DecodeRuneInString(pattern[i:])
+			}
+		case r == '%':
 			break Scan
 		}
+		i += n
 	}
 	return percentSymbol, pattern[0:i], pattern[i:]
 }
 
-func matchChunk(chunk, s string) (rest string, ok bool) {
+func matchChunk(chunk, s string, opts likeOptions) (rest string, ok bool) {
 	for len(chunk) > 0 {
 		if len(s) == 0 {
 			return
 		}
-		switch chunk[0] {
-		case '_':
-			_, n := utf8.DecodeLastRuneInString(s)
-			s = s[n:]
-			chunk = chunk[1:]
+		c, cn := utf8.DecodeRuneInString(chunk)
+		r, rn := utf8.DecodeRuneInString(s)
+		switch {
+		case opts.escape != 0 && c == opts.escape:
+			c, n := utf8.DecodeRuneInString(chunk[cn:])
+			if !equalRune(c, r, opts.ignoreCase) {
+				return
+			}
+			chunk = chunk[cn+n:]
+		case c == '_':
+			chunk = chunk[cn:]
 		default:
-			if chunk[0] != s[0] {
+			if !equalRune(c, r, opts.ignoreCase) {
 				return
 			}
-			s = s[1:]
-			chunk = chunk[1:]
+			chunk = chunk[cn:]
 		}
+		s = s[rn:]
 	}
 	return s, true
 }
+
+//equalRune 比较两个字符，ignoreCase为true时按Unicode的大小写折叠比较
+func equalRune(a, b rune, ignoreCase bool) bool {
+	if a == b {
+		return true
+	}
+	if !ignoreCase {
+		return false
+	}
+	for f := unicode.SimpleFold(a); f != a; f = unicode.SimpleFold(f) {
+		if f == b {
+			return true
+		}
+	}
+	return false
+}
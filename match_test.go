@@ -0,0 +1,41 @@
+package json_filter
+
+import (
+	"testing"
+)
+
+func TestLike(t *testing.T) {
+	getter := mapGetter{
+		"msg":  "disk 100% full",
+		"name": "ÄPFEL_kuchen",
+		"path": `C:\tmp`,
+	}
+	cases := []struct {
+		cond string
+		want bool
+	}{
+		{"msg like '%100%'", true},
+		{"msg like 'disk%'", true},
+		{"msg like 'Disk%'", false},
+		{"msg ilike 'DISK%FULL'", true},
+		{"msg not ilike 'DISK%'", false},
+		{"name ilike 'äpfel%'", true},
+		{"name like '_PFEL%'", true},
+		{"msg like '%100!%%' escape '!'", true},
+		{"msg like '%10!%%' escape '!'", false},
+		{`msg like '%100\%%' escape '\'`, true},
+		{`msg like '%10\%%' escape '\'`, false},
+		{`msg like '%100\%%' escape '\\'`, true},
+		{`name like '%\_%' escape '\'`, true},
+		{`msg like '%\_%' escape '\'`, false},
+		{`name ilike 'äpfel\_K%' escape '\'`, true},
+		{`path like '%\\\\%' escape '\'`, true},
+		{"msg not like '%!%%' escape '!'", false},
+		{"msg like '%''%' escape ''''", false},
+	}
+	for _, c := range cases {
+		if got := evalBool(t, c.cond, getter); got != c.want {
+			t.Errorf("%s = %v, want %v", c.cond, got, c.want)
+		}
+	}
+}
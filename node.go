@@ -1,9 +1,10 @@
 package json_filter
 
 import (
+	"errors"
 	"fmt"
-	"strconv"
-	"strings"
+	"regexp"
+	"time"
 
 	"github.com/shopspring/decimal"
 )
@@ -24,25 +25,42 @@ var (
 	_ BoolNoder = (*NodeGreaterThan)(nil)
 	_ BoolNoder = (*NodeGreaterEqual)(nil)
 	_ BoolNoder = (*NodeTrue)(nil)
+	_ BoolNoder = (*NodeNot)(nil)
+	_ BoolNoder = (*NodeBool)(nil)
+	_ BoolNoder = (*NodeNull)(nil)
+	_ BoolNoder = (*NodeIsTrue)(nil)
+	_ BoolNoder = (*NodeIsFalse)(nil)
+	_ BoolNoder = (*NodeField)(nil)
+	_ BoolNoder = (*NodeBetween)(nil)
+	_ BoolNoder = (*NodeNotBetween)(nil)
+	_ BoolNoder = (*NodeRegexp)(nil)
+	_ BoolNoder = (*NodeNotRegexp)(nil)
+	_ BoolNoder = (*NodeAny)(nil)
 
 	_ StringNoder = (*NodeString)(nil)
 
-	_ FloatNoder = (*NodeField)(nil)
-	_ FloatNoder = (*NodeNumber)(nil)
-	_ FloatNoder = (*NodePlus)(nil)
-	_ FloatNoder = (*NodeMinus)(nil)
-	_ FloatNoder = (*NodeMult)(nil)
-	_ FloatNoder = (*NodeDiv)(nil)
-	_ FloatNoder = (*NodeMod)(nil)
+	_ NumberNoder = (*NodeField)(nil)
+	_ NumberNoder = (*NodeNumber)(nil)
+	_ NumberNoder = (*NodePlus)(nil)
+	_ NumberNoder = (*NodeMinus)(nil)
+	_ NumberNoder = (*NodeMult)(nil)
+	_ NumberNoder = (*NodeDiv)(nil)
+	_ NumberNoder = (*NodeMod)(nil)
+	_ NumberNoder = (*NodeIntDiv)(nil)
+	_ NumberNoder = (*NodeNeg)(nil)
 
 	_ InterfaceNoder = (*NodeField)(nil)
 	_ InterfaceNoder = (*NodeString)(nil)
-	_ FloatNoder     = (*NodeNumber)(nil)
-	_ FloatNoder     = (*NodePlus)(nil)
-	_ FloatNoder     = (*NodeMinus)(nil)
-	_ FloatNoder     = (*NodeMult)(nil)
-	_ FloatNoder     = (*NodeDiv)(nil)
-	_ FloatNoder     = (*NodeMod)(nil)
+	_ InterfaceNoder = (*NodeNumber)(nil)
+	_ InterfaceNoder = (*NodePlus)(nil)
+	_ InterfaceNoder = (*NodeMinus)(nil)
+	_ InterfaceNoder = (*NodeMult)(nil)
+	_ InterfaceNoder = (*NodeDiv)(nil)
+	_ InterfaceNoder = (*NodeMod)(nil)
+	_ InterfaceNoder = (*NodeBool)(nil)
+	_ InterfaceNoder = (*NodeNull)(nil)
+	_ InterfaceNoder = (*NodeIntDiv)(nil)
+	_ InterfaceNoder = (*NodeNeg)(nil)
 )
 
 type NodeType int8
@@ -71,8 +89,33 @@ const (
 	NodeTypeDiv
 	NodeTypeMod
 	NodeTypeTrue
+	NodeTypeAggregate
+	NodeTypeNot
+	NodeTypeBool
+	NodeTypeIsTrue
+	NodeTypeIsFalse
+	NodeTypeBetween
+	NodeTypeNotBetween
+	NodeTypeRegexp
+	NodeTypeNotRegexp
+	NodeTypeFunction
+	NodeTypeIntDiv
+	NodeTypeNeg
+	NodeTypeInterval
+	NodeTypeCase
+	NodeTypeCoalesce
+	NodeTypeNullIf
+	NodeTypeIf
+	NodeTypeCast
+	NodeTypeIsMissing
+	NodeTypeIsNotMissing
+	NodeTypeAny
+	NodeTypeNull
 )
 
+//errDivisionByZero 除数为0
+var errDivisionByZero = errors.New("division by zero")
+
 type Noder interface {
 	Type() NodeType
 }
@@ -88,10 +131,11 @@ type StringNoder interface {
 	Str() string
 }
 
-type FloatNoder interface {
+//NumberNoder 数值节点，使用decimal计算，整数和小数都不会丢失精度
+type NumberNoder interface {
 	Noder
 	InterfaceNoder
-	Float(Getter) (float64, error)
+	Number(Getter) (decimal.Decimal, error)
 }
 
 type BoolNoder interface {
@@ -99,6 +143,12 @@ type BoolNoder interface {
 	Bool(Getter) (bool, error)
 }
 
+//ParentNoder 包含子节点的节点
+type ParentNoder interface {
+	Noder
+	Children() []Noder
+}
+
 type NodeString struct {
 	str string
 }
@@ -116,19 +166,19 @@ func (n NodeString) Interface(getter Getter) (interface{}, error) {
 }
 
 type NodeNumber struct {
-	f float64
+	d decimal.Decimal
 }
 
 func (n NodeNumber) Type() NodeType {
 	return NodeTypeNumber
 }
 
-func (n NodeNumber) Float(getter Getter) (float64, error) {
-	return n.f, nil
+func (n NodeNumber) Number(getter Getter) (decimal.Decimal, error) {
+	return n.d, nil
 }
 
 func (n NodeNumber) Interface(getter Getter) (interface{}, error) {
-	return n.f, nil
+	return numberValue(n.d), nil
 }
 
 type Getter interface {
@@ -137,32 +187,162 @@ type Getter interface {
 
 type NodeField struct {
 	key string
+	//multi 路径中有切片、*或..，值为所有匹配的值组成的数组
+	multi bool
 }
 
 func (n NodeField) Type() NodeType {
 	return NodeTypeField
 }
 
-func (n NodeField) Float(getter Getter) (float64, error) {
+func (n NodeField) Number(getter Getter) (decimal.Decimal, error) {
 	data, err := getter.Get(n.key)
 	if err != nil {
-		return 0, err
+		return decimal.Zero, err
 	}
-	f, fOK := data.(float64)
-	if fOK {
-		return f, nil
-	}
-	s, sOK := data.(string)
-	if !sOK {
-		return 0, fmt.Errorf("unsupported data type")
-	}
-	return strconv.ParseFloat(s, 64)
+	return toNumber(data)
 }
 
 func (n NodeField) Interface(getter Getter) (interface{}, error) {
 	return getter.Get(n.key)
 }
 
+//Bool 字段直接作为条件时，值必须是bool，字段不存在时为false
+func (n NodeField) Bool(getter Getter) (bool, error) {
+	return isTrue(n.logic(getter))
+}
+
+//logic 字段不存在或值为null时为null，可以匹配多个值时有一个为true即为true
+func (n NodeField) logic(getter Getter) (bool, bool, error) {
+	data, err := getter.Get(n.key)
+	if err != nil {
+		return false, false, err
+	}
+	values, ok := data.([]interface{})
+	if !n.multi || !ok {
+		values = []interface{}{data}
+	}
+	null := false
+	for _, v := range values {
+		switch b := v.(type) {
+		case nil:
+			null = true
+		case bool:
+			if b {
+				return true, false, nil
+			}
+		default:
+			return false, false, fmt.Errorf("%s is not a boolean: %v", n.key, v)
+		}
+	}
+	return false, null, nil
+}
+
+//NodeAny 条件中用到可以匹配多个值的字段时，对每个匹配的值分别计算条件，有一个成立即成立，
+//都不成立而有结果为null的时为null，没有匹配的值时不成立
+type NodeAny struct {
+	Key  string
+	Node BoolNoder
+}
+
+func (n NodeAny) Type() NodeType {
+	return NodeTypeAny
+}
+
+func (n NodeAny) Children() []Noder {
+	return []Noder{n.Node}
+}
+
+func (n NodeAny) Bool(getter Getter) (bool, error) {
+	return isTrue(n.logic(getter))
+}
+
+func (n NodeAny) logic(getter Getter) (bool, bool, error) {
+	data, err := getter.Get(n.Key)
+	if err != nil {
+		return false, false, err
+	}
+	values, ok := data.([]interface{})
+	if !ok {
+		return logicValue(n.Node, getter)
+	}
+	null := false
+	for _, v := range values {
+		value, isNull, err := logicValue(n.Node, matchGetter{Getter: getter, key: n.Key, value: v})
+		if err != nil {
+			return false, false, err
+		}
+		if value && !isNull {
+			return true, false, nil
+		}
+		null = null || isNull
+	}
+	return false, null, nil
+}
+
+//matchGetter 计算NodeAny的条件时，可以匹配多个值的字段取当前匹配的值，其他字段不变
+type matchGetter struct {
+	Getter
+	key   string
+	value interface{}
+}
+
+func (g matchGetter) Get(key string) (interface{}, error) {
+	if key == g.key {
+		return g.value, nil
+	}
+	return g.Getter.Get(key)
+}
+
+func (g matchGetter) Exists(key string) bool {
+	if key == g.key {
+		return true
+	}
+	exists, _ := fieldExists(g.Getter, key)
+	return exists
+}
+
+func (g matchGetter) Location() *time.Location {
+	return getterLocation(g.Getter)
+}
+
+//anyMatch 条件中有可以匹配多个值的字段时，用NodeAny包装条件，有多个这样的字段时逐个包装，
+//聚合函数中的字段不包装，and、or、not的每个条件已经分别包装过
+func anyMatch(n BoolNoder) BoolNoder {
+	switch n.(type) {
+	case *NodeAnd, *NodeOr, *NodeNot, *NodeAny:
+		return n
+	}
+	keys := make([]string, 0)
+	collectMultiFields(n, &keys)
+	for i := len(keys) - 1; i >= 0; i-- {
+		n = &NodeAny{Key: keys[i], Node: n}
+	}
+	return n
+}
+
+func collectMultiFields(n Noder, keys *[]string) {
+	switch node := n.(type) {
+	case *NodeField:
+		if !node.multi {
+			return
+		}
+		for _, k := range *keys {
+			if k == node.key {
+				return
+			}
+		}
+		*keys = append(*keys, node.key)
+	case *NodeAggregate, *NodeAny:
+	case ParentNoder:
+		for _, child := range node.Children() {
+			if child != nil {
+				collectMultiFields(child, keys)
+			}
+		}
+	}
+}
+
 type NodeAnd struct {
 	Left  BoolNoder
 	Right BoolNoder
@@ -172,22 +352,25 @@ func (n NodeAnd) Type() NodeType {
 	return NodeTypeAnd
 }
 
+func (n NodeAnd) Children() []Noder {
+	return []Noder{n.Left, n.Right}
+}
+
 func (n NodeAnd) Bool(getter Getter) (bool, error) {
-	leftOK, leftErr := n.Left.Bool(getter)
-	if leftErr != nil {
-		return false, leftErr
-	}
-	if !leftOK {
-		return false, nil
-	}
-	rightOK, rightErr := n.Right.Bool(getter)
-	if rightErr != nil {
-		return false, rightErr
+	return isTrue(n.logic(getter))
+}
+
+//logic 有一边为false时为false，否则有一边为null时为null
+func (n NodeAnd) logic(getter Getter) (bool, bool, error) {
+	left, leftNull, err := logicValue(n.Left, getter)
+	if err != nil || !left && !leftNull {
+		return false, false, err
 	}
-	if !rightOK {
-		return false, nil
+	right, rightNull, err := logicValue(n.Right, getter)
+	if err != nil || !right && !rightNull {
+		return false, false, err
 	}
-	return true, nil
+	return !leftNull && !rightNull, leftNull || rightNull, nil
 }
 
 type NodeOr struct {
@@ -199,46 +382,94 @@ func (n NodeOr) Type() NodeType {
 	return NodeTypeOr
 }
 
+func (n NodeOr) Children() []Noder {
+	return []Noder{n.Left, n.Right}
+}
+
 func (n NodeOr) Bool(getter Getter) (bool, error) {
-	leftOK, leftErr := n.Left.Bool(getter)
-	if leftErr != nil {
-		return false, leftErr
-	}
-	if leftOK {
-		return true, nil
+	return isTrue(n.logic(getter))
+}
+
+//logic 有一边为true时为true，否则有一边为null时为null
+func (n NodeOr) logic(getter Getter) (bool, bool, error) {
+	left, leftNull, err := logicValue(n.Left, getter)
+	if err != nil || left && !leftNull {
+		return left, false, err
 	}
-	rightOK, rightErr := n.Right.Bool(getter)
-	if rightErr != nil {
-		return false, rightErr
+	right, rightNull, err := logicValue(n.Right, getter)
+	if err != nil || right && !rightNull {
+		return right, false, err
 	}
-	return rightOK, nil
+	return false, leftNull || rightNull, nil
 }
 
+//NodeIn x in (a, b, ...)，List为空时判断数组Array中是否包含x，比较规则与=相同
 type NodeIn struct {
-	Key   string
-	Slice []interface{}
+	Value InterfaceNoder
+	List  []InterfaceNoder
+	//Array x in tags中的数组，值不是数组时等同于x = tags
+	Array InterfaceNoder
 }
 
 func (n NodeIn) Type() NodeType {
 	return NodeTypeIn
 }
 
+func (n NodeIn) Children() []Noder {
+	children := []Noder{n.Value}
+	for _, item := range n.List {
+		children = append(children, item)
+	}
+	if n.Array != nil {
+		children = append(children, n.Array)
+	}
+	return children
+}
+
 func (n NodeIn) Bool(getter Getter) (bool, error) {
-	data, err := getter.Get(n.Key)
-	if err != nil {
-		return false, err
+	return isTrue(n.logic(getter))
+}
+
+//logic x为null，或者没有相等的值而列表中有null时为null
+func (n NodeIn) logic(getter Getter) (bool, bool, error) {
+	data, err := nodeValue(n.Value, getter)
+	if err != nil || data == nil {
+		return false, data == nil, err
 	}
-	for _, item := range n.Slice {
-		if item == data {
-			return true, nil
+	loc := getterLocation(getter)
+	values := make([]interface{}, 0, len(n.List))
+	if n.Array != nil {
+		array, err := n.Array.Interface(getter)
+		if err != nil || array == nil {
+			return false, array == nil, err
+		}
+		items, ok := array.([]interface{})
+		if !ok {
+			items = []interface{}{array}
+		}
+		values = items
+	} else {
+		for _, item := range n.List {
+			v, err := nodeValue(item, getter)
+			if err != nil {
+				return false, false, err
+			}
+			values = append(values, v)
 		}
 	}
-	return false, nil
+	null := false
+	for _, v := range values {
+		if v == nil {
+			null = true
+		} else if equalValues(data, v, loc) {
+			return true, false, nil
+		}
+	}
+	return false, null, nil
 }
 
 type NodeNotIn struct {
-	Key   string
-	Slice []interface{}
+	NodeIn
 }
 
 func (n NodeNotIn) Type() NodeType {
@@ -246,28 +477,29 @@ func (n NodeNotIn) Type() NodeType {
 }
 
 func (n NodeNotIn) Bool(getter Getter) (bool, error) {
-	data, err := getter.Get(n.Key)
-	if err != nil {
-		return false, err
-	}
-	for _, item := range n.Slice {
-		if item == data {
-			return false, nil
-		}
-	}
-	return true, nil
+	return isTrue(n.logic(getter))
 }
 
+func (n NodeNotIn) logic(getter Getter) (bool, bool, error) {
+	value, null, err := n.NodeIn.logic(getter)
+	return !value && !null, null, err
+}
+
+//NodeIsNull x is null，字段不存在或值为null时都成立
 type NodeIsNull struct {
-	Key string
+	Value InterfaceNoder
 }
 
 func (n NodeIsNull) Type() NodeType {
 	return NodeTypeIsNULL
 }
 
+func (n NodeIsNull) Children() []Noder {
+	return []Noder{n.Value}
+}
+
 func (n NodeIsNull) Bool(getter Getter) (bool, error) {
-	data, err := getter.Get(n.Key)
+	data, err := nodeValue(n.Value, getter)
 	if err != nil {
 		return false, err
 	}
@@ -275,61 +507,128 @@ func (n NodeIsNull) Bool(getter Getter) (bool, error) {
 }
 
 type NodeIsNotNull struct {
-	Key string
+	Value InterfaceNoder
 }
 
 func (n NodeIsNotNull) Type() NodeType {
 	return NodeTypeIsNotNULL
 }
 
+func (n NodeIsNotNull) Children() []Noder {
+	return []Noder{n.Value}
+}
+
 func (n NodeIsNotNull) Bool(getter Getter) (bool, error) {
-	data, err := getter.Get(n.Key)
+	data, err := nodeValue(n.Value, getter)
 	if err != nil {
 		return false, err
 	}
 	return data != nil, nil
 }
 
-type NodeLike struct {
+//NodeIsMissing x is missing，字段不存在时成立，字段的值为null时不成立
+type NodeIsMissing struct {
 	Key string
-	Str string
+}
+
+func (n NodeIsMissing) Type() NodeType {
+	return NodeTypeIsMissing
+}
+
+func (n NodeIsMissing) Bool(getter Getter) (bool, error) {
+	exists, err := fieldExists(getter, n.Key)
+	return !exists, err
+}
+
+//NodeIsNotMissing x is not missing或exists(x)，字段存在时成立，值为null时也成立
+type NodeIsNotMissing struct {
+	Key string
+}
+
+func (n NodeIsNotMissing) Type() NodeType {
+	return NodeTypeIsNotMissing
+}
+
+func (n NodeIsNotMissing) Bool(getter Getter) (bool, error) {
+	return fieldExists(getter, n.Key)
+}
+
+//existGetter 可以区分字段不存在和字段的值为null的Getter
+type existGetter interface {
+	Exists(key string) bool
+}
+
+//fieldExists 判断字段是否存在，getter不能区分时值不为null即存在
+func fieldExists(getter Getter, key string) (bool, error) {
+	if g, ok := getter.(existGetter); ok {
+		return g.Exists(key), nil
+	}
+	data, err := getter.Get(key)
+	return data != nil, err
+}
+
+type NodeLike struct {
+	Value InterfaceNoder
+	Str   string
+	//Escape like ... escape指定的转义字符，为0时没有转义字符
+	Escape rune
+	//IgnoreCase 是否忽略大小写(ilike)
+	IgnoreCase bool
 }
 
 func (n NodeLike) Type() NodeType {
 	return NodeTypeLike
 }
 
+func (n NodeLike) Children() []Noder {
+	return []Noder{n.Value}
+}
+
 func (n NodeLike) Bool(getter Getter) (bool, error) {
-	data, err := getter.Get(n.Key)
-	if err != nil {
-		return false, err
+	return isTrue(n.logic(getter))
+}
+
+func (n NodeLike) logic(getter Getter) (bool, bool, error) {
+	data, err := n.Value.Interface(getter)
+	if err != nil || data == nil {
+		return false, data == nil, err
 	}
 	str, ok := data.(string)
 	if !ok {
-		return false, nil
+		return false, false, nil
 	}
-	return match(n.Str, str), nil
+	return match(n.Str, str, likeOptions{escape: n.Escape, ignoreCase: n.IgnoreCase}), false, nil
 }
 
 type NodeNotLike struct {
-	Key string
-	Str string
+	Value      InterfaceNoder
+	Str        string
+	Escape     rune
+	IgnoreCase bool
 }
 
 func (n NodeNotLike) Type() NodeType {
 	return NodeTypeNotLike
 }
 
+func (n NodeNotLike) Children() []Noder {
+	return []Noder{n.Value}
+}
+
 func (n NodeNotLike) Bool(getter Getter) (bool, error) {
-	data, err := getter.Get(n.Key)
-	if err != nil {
-		return false, err
+	return isTrue(n.logic(getter))
+}
+
+func (n NodeNotLike) logic(getter Getter) (bool, bool, error) {
+	data, err := n.Value.Interface(getter)
+	if err != nil || data == nil {
+		return false, data == nil, err
 	}
 	str, ok := data.(string)
 	if !ok {
-		return false, nil
+		return false, false, nil
 	}
-	return !match(n.Str, str), nil
+	return !match(n.Str, str, likeOptions{escape: n.Escape, ignoreCase: n.IgnoreCase}), false, nil
 }
 
 type NodeEqual struct {
@@ -341,29 +640,20 @@ func (n NodeEqual) Type() NodeType {
 	return NodeTypeEqual
 }
 
+func (n NodeEqual) Children() []Noder {
+	return []Noder{n.Left, n.Right}
+}
+
 func (n NodeEqual) Bool(getter Getter) (bool, error) {
-	leftI, err := n.Left.Interface(getter)
-	if err != nil {
-		return false, err
-	}
-	rightI, err := n.Right.Interface(getter)
-	if err != nil {
-		return false, err
-	}
-	str1, ok1 := leftI.(string)
-	str2, ok2 := rightI.(string)
-	if ok1 != ok2 {
-		return false, nil
-	}
-	if ok1 {
-		return str1 == str2, nil
-	}
-	num1, ok1 := leftI.(float64)
-	num2, ok2 := rightI.(float64)
-	if ok1 != ok2 {
-		return false, nil
+	return isTrue(n.logic(getter))
+}
+
+func (n NodeEqual) logic(getter Getter) (bool, bool, error) {
+	c, ok, null, err := compareNodes(n.Left, n.Right, getter)
+	if err != nil || null {
+		return false, null, err
 	}
-	return num1 == num2, nil
+	return (ok && c == 0), false, nil
 }
 
 type NodeNotEqual struct {
@@ -375,243 +665,330 @@ func (n NodeNotEqual) Type() NodeType {
 	return NodeTypeNotEqual
 }
 
+func (n NodeNotEqual) Children() []Noder {
+	return []Noder{n.Left, n.Right}
+}
+
 func (n NodeNotEqual) Bool(getter Getter) (bool, error) {
-	leftI, err := n.Left.Interface(getter)
-	if err != nil {
-		return false, err
-	}
-	rightI, err := n.Right.Interface(getter)
-	if err != nil {
-		return false, err
-	}
-	str1, ok1 := leftI.(string)
-	str2, ok2 := rightI.(string)
-	if ok1 != ok2 {
-		return true, nil
-	}
-	if ok1 {
-		return str1 != str2, nil
-	}
-	num1, ok1 := leftI.(float64)
-	num2, ok2 := rightI.(float64)
-	if ok1 != ok2 {
-		return true, nil
+	return isTrue(n.logic(getter))
+}
+
+func (n NodeNotEqual) logic(getter Getter) (bool, bool, error) {
+	c, ok, null, err := compareNodes(n.Left, n.Right, getter)
+	if err != nil || null {
+		return false, null, err
 	}
-	return num1 != num2, nil
+	return !(ok && c == 0), false, nil
 }
 
 type NodeLessThan struct {
-	Left  FloatNoder
-	Right FloatNoder
+	Left  InterfaceNoder
+	Right InterfaceNoder
 }
 
 func (n NodeLessThan) Type() NodeType {
 	return NodeTypeLessThan
 }
 
+func (n NodeLessThan) Children() []Noder {
+	return []Noder{n.Left, n.Right}
+}
+
 func (n NodeLessThan) Bool(getter Getter) (bool, error) {
-	leftF, err := n.Left.Float(getter)
-	if err != nil {
-		return false, err
-	}
-	rightF, err := n.Right.Float(getter)
-	if err != nil {
-		return false, err
+	return isTrue(n.logic(getter))
+}
+
+func (n NodeLessThan) logic(getter Getter) (bool, bool, error) {
+	c, ok, null, err := compareNodes(n.Left, n.Right, getter)
+	if err != nil || null {
+		return false, null, err
 	}
-	return leftF < rightF, nil
+	return ok && c < 0, false, nil
 }
 
 type NodeLessEqual struct {
-	Left  FloatNoder
-	Right FloatNoder
+	Left  InterfaceNoder
+	Right InterfaceNoder
 }
 
 func (n NodeLessEqual) Type() NodeType {
 	return NodeTypeLessEqual
 }
 
+func (n NodeLessEqual) Children() []Noder {
+	return []Noder{n.Left, n.Right}
+}
+
 func (n NodeLessEqual) Bool(getter Getter) (bool, error) {
-	leftF, err := n.Left.Float(getter)
-	if err != nil {
-		return false, err
-	}
-	rightF, err := n.Right.Float(getter)
-	if err != nil {
-		return false, err
+	return isTrue(n.logic(getter))
+}
+
+func (n NodeLessEqual) logic(getter Getter) (bool, bool, error) {
+	c, ok, null, err := compareNodes(n.Left, n.Right, getter)
+	if err != nil || null {
+		return false, null, err
 	}
-	return leftF <= rightF, nil
+	return ok && c <= 0, false, nil
 }
 
 type NodeGreaterThan struct {
-	Left  FloatNoder
-	Right FloatNoder
+	Left  InterfaceNoder
+	Right InterfaceNoder
 }
 
 func (n NodeGreaterThan) Type() NodeType {
 	return NodeTypeGreaterThan
 }
 
+func (n NodeGreaterThan) Children() []Noder {
+	return []Noder{n.Left, n.Right}
+}
+
 func (n NodeGreaterThan) Bool(getter Getter) (bool, error) {
-	leftF, err := n.Left.Float(getter)
-	if err != nil {
-		return false, err
-	}
-	rightF, err := n.Right.Float(getter)
-	if err != nil {
-		return false, err
+	return isTrue(n.logic(getter))
+}
+
+func (n NodeGreaterThan) logic(getter Getter) (bool, bool, error) {
+	c, ok, null, err := compareNodes(n.Left, n.Right, getter)
+	if err != nil || null {
+		return false, null, err
 	}
-	return leftF > rightF, nil
+	return ok && c > 0, false, nil
 }
 
 type NodeGreaterEqual struct {
-	Left  FloatNoder
-	Right FloatNoder
+	Left  InterfaceNoder
+	Right InterfaceNoder
 }
 
 func (n NodeGreaterEqual) Type() NodeType {
 	return NodeTypeGreaterEqual
 }
 
+func (n NodeGreaterEqual) Children() []Noder {
+	return []Noder{n.Left, n.Right}
+}
+
 func (n NodeGreaterEqual) Bool(getter Getter) (bool, error) {
-	leftF, err := n.Left.Float(getter)
-	if err != nil {
-		return false, err
-	}
-	rightF, err := n.Right.Float(getter)
-	if err != nil {
-		return false, err
+	return isTrue(n.logic(getter))
+}
+
+func (n NodeGreaterEqual) logic(getter Getter) (bool, bool, error) {
+	c, ok, null, err := compareNodes(n.Left, n.Right, getter)
+	if err != nil || null {
+		return false, null, err
 	}
-	return leftF >= rightF, nil
+	return ok && c >= 0, false, nil
 }
 
 type NodePlus struct {
-	Left  FloatNoder
-	Right FloatNoder
+	Left  InterfaceNoder
+	Right InterfaceNoder
 }
 
 func (n NodePlus) Type() NodeType {
 	return NodeTypePlus
 }
 
-func (n NodePlus) Float(getter Getter) (float64, error) {
-	leftFloat, err := n.Left.Float(getter)
-	if err != nil {
-		return 0, err
-	}
-	rightFloat, err := n.Right.Float(getter)
-	if err != nil {
-		return 0, err
-	}
-	num, _ := decimal.NewFromFloat(leftFloat).Add(decimal.NewFromFloat(rightFloat)).Float64()
-	return num, nil
+func (n NodePlus) Children() []Noder {
+	return []Noder{n.Left, n.Right}
+}
+
+func (n NodePlus) value(getter Getter) (interface{}, error) {
+	return additive(n.Left, n.Right, 1, getter)
+}
+
+func (n NodePlus) Number(getter Getter) (decimal.Decimal, error) {
+	return numberResult(n.value(getter))
 }
 
 func (n NodePlus) Interface(getter Getter) (interface{}, error) {
-	return n.Float(getter)
+	return interfaceResult(n.value(getter))
 }
 
 type NodeMinus struct {
-	Left  FloatNoder
-	Right FloatNoder
+	Left  InterfaceNoder
+	Right InterfaceNoder
 }
 
 func (n NodeMinus) Type() NodeType {
 	return NodeTypeMinus
 }
 
-func (n NodeMinus) Float(getter Getter) (float64, error) {
-	leftFloat, err := n.Left.Float(getter)
-	if err != nil {
-		return 0, err
-	}
-	rightFloat, err := n.Right.Float(getter)
-	if err != nil {
-		return 0, err
-	}
-	num, _ := decimal.NewFromFloat(leftFloat).Sub(decimal.NewFromFloat(rightFloat)).Float64()
-	return num, nil
+func (n NodeMinus) Children() []Noder {
+	return []Noder{n.Left, n.Right}
+}
+
+func (n NodeMinus) value(getter Getter) (interface{}, error) {
+	return additive(n.Left, n.Right, -1, getter)
+}
+
+func (n NodeMinus) Number(getter Getter) (decimal.Decimal, error) {
+	return numberResult(n.value(getter))
 }
 
 func (n NodeMinus) Interface(getter Getter) (interface{}, error) {
-	return n.Float(getter)
+	return interfaceResult(n.value(getter))
 }
 
 type NodeMult struct {
-	Left  FloatNoder
-	Right FloatNoder
+	Left  NumberNoder
+	Right NumberNoder
 }
 
 func (n NodeMult) Type() NodeType {
 	return NodeTypeMult
 }
 
-func (n NodeMult) Float(getter Getter) (float64, error) {
-	leftFloat, err := n.Left.Float(getter)
-	if err != nil {
-		return 0, err
-	}
-	rightFloat, err := n.Right.Float(getter)
-	if err != nil {
-		return 0, err
+func (n NodeMult) Children() []Noder {
+	return []Noder{n.Left, n.Right}
+}
+
+func (n NodeMult) value(getter Getter) (interface{}, error) {
+	x, y, null, err := numberOperands(n.Left, n.Right, getter)
+	if err != nil || null {
+		return nil, err
 	}
-	num, _ := decimal.NewFromFloat(leftFloat).Mul(decimal.NewFromFloat(rightFloat)).Float64()
-	return num, nil
+	return x.Mul(y), nil
+}
+
+func (n NodeMult) Number(getter Getter) (decimal.Decimal, error) {
+	return numberResult(n.value(getter))
 }
 
 func (n NodeMult) Interface(getter Getter) (interface{}, error) {
-	return n.Float(getter)
+	return interfaceResult(n.value(getter))
 }
 
 type NodeDiv struct {
-	Left  FloatNoder
-	Right FloatNoder
+	Left  NumberNoder
+	Right NumberNoder
 }
 
 func (n NodeDiv) Type() NodeType {
 	return NodeTypeDiv
 }
 
-func (n NodeDiv) Float(getter Getter) (float64, error) {
-	leftFloat, err := n.Left.Float(getter)
-	if err != nil {
-		return 0, err
+func (n NodeDiv) Children() []Noder {
+	return []Noder{n.Left, n.Right}
+}
+
+func (n NodeDiv) value(getter Getter) (interface{}, error) {
+	x, y, null, err := numberOperands(n.Left, n.Right, getter)
+	if err != nil || null {
+		return nil, err
 	}
-	rightFloat, err := n.Right.Float(getter)
-	if err != nil {
-		return 0, err
+	if y.IsZero() {
+		return nil, errDivisionByZero
 	}
-	num, _ := decimal.NewFromFloat(leftFloat).Div(decimal.NewFromFloat(rightFloat)).Float64()
-	return num, nil
+	return x.Div(y), nil
+}
+
+func (n NodeDiv) Number(getter Getter) (decimal.Decimal, error) {
+	return numberResult(n.value(getter))
 }
 
 func (n NodeDiv) Interface(getter Getter) (interface{}, error) {
-	return n.Float(getter)
+	return interfaceResult(n.value(getter))
 }
 
 type NodeMod struct {
-	Left  FloatNoder
-	Right FloatNoder
+	Left  NumberNoder
+	Right NumberNoder
 }
 
 func (n NodeMod) Type() NodeType {
 	return NodeTypeMod
 }
 
-func (n NodeMod) Float(getter Getter) (float64, error) {
-	leftFloat, err := n.Left.Float(getter)
-	if err != nil {
-		return 0, err
+func (n NodeMod) Children() []Noder {
+	return []Noder{n.Left, n.Right}
+}
+
+func (n NodeMod) value(getter Getter) (interface{}, error) {
+	x, y, null, err := numberOperands(n.Left, n.Right, getter)
+	if err != nil || null {
+		return nil, err
 	}
-	rightFloat, err := n.Right.Float(getter)
-	if err != nil {
-		return 0, err
+	if y.IsZero() {
+		return nil, errDivisionByZero
 	}
-	num, _ := decimal.NewFromFloat(leftFloat).Mod(decimal.NewFromFloat(rightFloat)).Float64()
-	return num, nil
+	return x.Mod(y), nil
+}
+
+func (n NodeMod) Number(getter Getter) (decimal.Decimal, error) {
+	return numberResult(n.value(getter))
 }
 
 func (n NodeMod) Interface(getter Getter) (interface{}, error) {
-	return n.Float(getter)
+	return interfaceResult(n.value(getter))
+}
+
+//NodeIntDiv 整数除法a div b，结果向0取整
+type NodeIntDiv struct {
+	Left  NumberNoder
+	Right NumberNoder
+}
+
+func (n NodeIntDiv) Type() NodeType {
+	return NodeTypeIntDiv
+}
+
+func (n NodeIntDiv) Children() []Noder {
+	return []Noder{n.Left, n.Right}
+}
+
+func (n NodeIntDiv) value(getter Getter) (interface{}, error) {
+	x, y, null, err := numberOperands(n.Left, n.Right, getter)
+	if err != nil || null {
+		return nil, err
+	}
+	if y.IsZero() {
+		return nil, errDivisionByZero
+	}
+	q, _ := x.QuoRem(y, 0)
+	return q, nil
+}
+
+func (n NodeIntDiv) Number(getter Getter) (decimal.Decimal, error) {
+	return numberResult(n.value(getter))
+}
+
+func (n NodeIntDiv) Interface(getter Getter) (interface{}, error) {
+	return interfaceResult(n.value(getter))
+}
+
+//NodeNeg 取负数-x
+type NodeNeg struct {
+	Node NumberNoder
+}
+
+func (n NodeNeg) Type() NodeType {
+	return NodeTypeNeg
+}
+
+func (n NodeNeg) Children() []Noder {
+	return []Noder{n.Node}
+}
+
+func (n NodeNeg) value(getter Getter) (interface{}, error) {
+	data, err := nodeValue(n.Node, getter)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	d, err := toNumber(data)
+	if err != nil {
+		return nil, err
+	}
+	return d.Neg(), nil
+}
+
+func (n NodeNeg) Number(getter Getter) (decimal.Decimal, error) {
+	return numberResult(n.value(getter))
+}
+
+func (n NodeNeg) Interface(getter Getter) (interface{}, error) {
+	return interfaceResult(n.value(getter))
 }
 
 type NodeTrue struct {
@@ -625,283 +1002,362 @@ func (n NodeTrue) Bool(getter Getter) (bool, error) {
 	return true, nil
 }
 
-func parseTokens(tokens []*Token) (Noder, error) {
-	if len(tokens) == 0 {
-		return nil, fmt.Errorf("tokens is empty")
-	}
-	var parenDepth int
-	if len(tokens) == 1 {
-		switch tokens[0].Type {
-		case TokenTypeString:
-			return &NodeString{
-				str: tokens[0].Str,
-			}, nil
-		case TokenTypeNumber:
-			f, _ := strconv.ParseFloat(tokens[0].Str, 64)
-			return &NodeNumber{
-				f: f,
-			}, nil
-		default:
-			return &NodeField{
-				key: tokens[0].Str,
-			}, nil
+type NodeNot struct {
+	Node BoolNoder
+}
+
+func (n NodeNot) Type() NodeType {
+	return NodeTypeNot
+}
+
+func (n NodeNot) Children() []Noder {
+	return []Noder{n.Node}
+}
+
+func (n NodeNot) Bool(getter Getter) (bool, error) {
+	return isTrue(n.logic(getter))
+}
+
+//logic not null仍为null
+func (n NodeNot) logic(getter Getter) (bool, bool, error) {
+	value, null, err := logicValue(n.Node, getter)
+	return !value && !null, null, err
+}
+
+//NodeBool true或false
+type NodeBool struct {
+	b bool
+}
+
+func (n NodeBool) Type() NodeType {
+	return NodeTypeBool
+}
+
+func (n NodeBool) Bool(getter Getter) (bool, error) {
+	return n.b, nil
+}
+
+func (n NodeBool) Interface(getter Getter) (interface{}, error) {
+	return n.b, nil
+}
+
+//NodeNull null常量
+type NodeNull struct {
+}
+
+func (n NodeNull) Type() NodeType {
+	return NodeTypeNull
+}
+
+func (n NodeNull) Interface(getter Getter) (interface{}, error) {
+	return nil, nil
+}
+
+func (n NodeNull) Number(getter Getter) (decimal.Decimal, error) {
+	return toNumber(nil)
+}
+
+func (n NodeNull) Bool(getter Getter) (bool, error) {
+	return false, nil
+}
+
+//logic null作为条件时结果未知
+func (n NodeNull) logic(getter Getter) (bool, bool, error) {
+	return false, true, nil
+}
+
+//boolValue 计算节点的真假，有值的节点只有值为bool时ok才为true
+func boolValue(n Noder, getter Getter) (value bool, ok bool, err error) {
+	if i, isInterface := n.(InterfaceNoder); isInterface {
+		data, err := i.Interface(getter)
+		if err != nil {
+			return false, false, err
 		}
+		value, ok = data.(bool)
+		return value, ok, nil
 	}
-	pStartIndex := 0
-	for i := 0; i < len(tokens); i++ {
-		t := tokens[i]
-		if isLeftParen(t) {
-			if parenDepth == 0 {
-				pStartIndex = i
-			}
-			parenDepth++
+	value, null, err := logicValue(n.(BoolNoder), getter)
+	return value, err == nil && !null, err
+}
+
+//logicNoder 按三值逻辑计算的条件节点，结果可以是true、false或null(未知)
+type logicNoder interface {
+	BoolNoder
+	logic(Getter) (value bool, null bool, err error)
+}
+
+//logicValue 按三值逻辑计算条件，null为true时结果未知，如与null比较、值为null的字段作为条件
+func logicValue(n BoolNoder, getter Getter) (value bool, null bool, err error) {
+	switch node := n.(type) {
+	case logicNoder:
+		return node.logic(getter)
+	case InterfaceNoder:
+		data, err := node.Interface(getter)
+		if err != nil {
+			return false, false, err
 		}
-		if isRightParen(t) {
-			parenDepth--
-			if parenDepth == 0 {
-				//如果整个列表的开头和结束都是括号，则去掉后重新解析
-				if i == len(tokens)-1 && pStartIndex == 0 {
-					return parseTokens(tokens[1:i])
-				}
-			}
-		}
-		if parenDepth == 0 && (isOr(t) || isAnd(t)) {
-			left, err := parseTokens(tokens[0:i])
-			if err != nil {
-				return nil, err
-			}
-			leftB, leftOK := left.(BoolNoder)
-			if !leftOK {
-				return nil, fmt.Errorf("left is not BoolNoder")
-			}
-			right, err := parseTokens(tokens[i+1:])
-			if err != nil {
-				return nil, err
-			}
-			rightB, rightOK := right.(BoolNoder)
-			if !rightOK {
-				return nil, fmt.Errorf("right is not BoolNoder")
-			}
-			if isOr(t) {
-				return NodeOr{
-					Left:  leftB,
-					Right: rightB,
-				}, nil
-			} else {
-				return &NodeAnd{
-					Left:  leftB,
-					Right: rightB,
-				}, nil
-			}
+		switch b := data.(type) {
+		case nil:
+			return false, true, nil
+		case bool:
+			return b, false, nil
 		}
 	}
-	for i := 0; i < len(tokens); i++ {
-		t := tokens[i]
-		if isLeftParen(t) {
-			if parenDepth == 0 {
-				pStartIndex = i
-			}
-			parenDepth++
-		}
-		if isRightParen(t) {
-			parenDepth--
-			if parenDepth == 0 {
-				//如果整个列表的开头和结束都是括号，则去掉后重新解析
-				if i == len(tokens)-1 && pStartIndex == 0 {
-					return parseTokens(tokens[1:i])
-				}
-			}
-		}
-		if parenDepth == 0 && isLogicalOperator(t) {
-			left, err := parseTokens(tokens[0:i])
-			if err != nil {
-				return nil, err
-			}
-			right, err := parseTokens(tokens[i+1:])
-			if err != nil {
-				return nil, err
-			}
-			if t.Str == OperatorEqual || t.Str == OperatorNotEqual || t.Str == OperatorNotEqual2 {
-				leftI, leftOK := left.(InterfaceNoder)
-				rightI, rightOK := right.(InterfaceNoder)
-				if !leftOK {
-					return nil, fmt.Errorf("left is not InterfaceNoder")
-				}
-				if !rightOK {
-					return nil, fmt.Errorf("right is not InterfaceNoder")
-				}
-				switch t.Str {
-				case OperatorEqual:
-					return NodeEqual{
-						Left:  leftI,
-						Right: rightI,
-					}, nil
-				case OperatorNotEqual, OperatorNotEqual2:
-					return NodeNotEqual{
-						Left:  leftI,
-						Right: rightI,
-					}, nil
-				}
-			}
-			leftF, leftOK := left.(FloatNoder)
-			if !leftOK {
-				return nil, fmt.Errorf("left is not FloatNoder")
-			}
-			rightF, rightOK := right.(FloatNoder)
-			if !rightOK {
-				return nil, fmt.Errorf("right is not FloatNoder")
-			}
-			switch t.Str {
-			case OperatorLessThan:
-				return NodeLessThan{
-					Left:  leftF,
-					Right: rightF,
-				}, nil
-			case OperatorLessEqual:
-				return NodeLessEqual{
-					Left:  leftF,
-					Right: rightF,
-				}, nil
-			case OperatorGreaterThan:
-				return NodeGreaterThan{
-					Left:  leftF,
-					Right: rightF,
-				}, nil
-			case OperatorGreaterEqual:
-				return NodeGreaterEqual{
-					Left:  leftF,
-					Right: rightF,
-				}, nil
-			}
-			return nil, fmt.Errorf("unknow node type")
-		}
+	value, err = n.Bool(getter)
+	return value, false, err
+}
+
+//isTrue 条件的结果为null时不成立
+func isTrue(value, null bool, err error) (bool, error) {
+	return value && !null, err
+}
+
+//NodeIsTrue x is true，x不是bool时为false
+type NodeIsTrue struct {
+	Node Noder
+}
+
+func (n NodeIsTrue) Type() NodeType {
+	return NodeTypeIsTrue
+}
+
+func (n NodeIsTrue) Children() []Noder {
+	return []Noder{n.Node}
+}
+
+func (n NodeIsTrue) Bool(getter Getter) (bool, error) {
+	value, ok, err := boolValue(n.Node, getter)
+	if err != nil {
+		return false, err
 	}
-	for i := 0; i < len(tokens); i++ {
-		t := tokens[i]
-		if isArithmeticOperator(t) {
-			left, err := parseTokens(tokens[0:i])
-			if err != nil {
-				return nil, err
-			}
-			right, err := parseTokens(tokens[i+1:])
-			if err != nil {
-				return nil, err
-			}
-			leftF, leftOK := left.(FloatNoder)
-			if !leftOK {
-				return nil, fmt.Errorf("left is not FloatNoder")
-			}
-			rightF, rightOK := right.(FloatNoder)
-			if !rightOK {
-				return nil, fmt.Errorf("right is not FloatNoder")
-			}
-			switch t.Str {
-			case OperatorPlus:
-				return &NodePlus{
-					Left:  leftF,
-					Right: rightF,
-				}, nil
-			case OperatorMinus:
-				return &NodeMinus{
-					Left:  leftF,
-					Right: rightF,
-				}, nil
-			case OperatorMult:
-				return &NodeMult{
-					Left:  leftF,
-					Right: rightF,
-				}, nil
-			case OperatorDiv:
-				return &NodeDiv{
-					Left:  leftF,
-					Right: rightF,
-				}, nil
-			case OperatorMod:
-				return &NodeMod{
-					Left:  leftF,
-					Right: rightF,
-				}, nil
-			}
-			return nil, fmt.Errorf("unknow node type")
-		}
+	return ok && value, nil
+}
+
+//NodeIsFalse x is false，x不是bool时为false
+type NodeIsFalse struct {
+	Node Noder
+}
+
+func (n NodeIsFalse) Type() NodeType {
+	return NodeTypeIsFalse
+}
+
+func (n NodeIsFalse) Children() []Noder {
+	return []Noder{n.Node}
+}
+
+func (n NodeIsFalse) Bool(getter Getter) (bool, error) {
+	value, ok, err := boolValue(n.Node, getter)
+	if err != nil {
+		return false, err
 	}
-	//is null
-	if len(tokens) == 3 && strings.ToLower(tokens[1].Str) == KeywordIs && strings.ToLower(tokens[2].Str) == KeywordNULL {
-		return &NodeIsNull{
-			Key: tokens[0].Str,
-		}, nil
-	}
-	//is not null
-	if len(tokens) == 4 && strings.ToLower(tokens[1].Str) == KeywordIs && strings.ToLower(tokens[2].Str) == KeywordNot && strings.ToLower(tokens[3].Str) == KeywordNULL {
-		return &NodeIsNotNull{
-			Key: tokens[0].Str,
-		}, nil
-	}
-	//like
-	if len(tokens) == 3 && strings.ToLower(tokens[1].Str) == KeywordLike && tokens[2].Type == TokenTypeString {
-		return &NodeLike{
-			Key: tokens[0].Str,
-			Str: tokens[2].Str,
-		}, nil
-	}
-	//not like
-	if len(tokens) == 4 && strings.ToLower(tokens[1].Str) == KeywordNot && strings.ToLower(tokens[2].Str) == KeywordLike && tokens[3].Type == TokenTypeString {
-		return &NodeNotLike{
-			Key: tokens[0].Str,
-			Str: tokens[3].Str,
-		}, nil
-	}
-	// in
-	if len(tokens) >= 5 && strings.ToLower(tokens[1].Str) == KeywordIn && isLeftParen(tokens[2]) && isRightParen(tokens[len(tokens)-1]) {
-		data := make([]interface{}, 0)
-		for j := 3; j < len(tokens)-1; j++ {
-			data = append(data, tokens[j].Str)
+	return ok && !value, nil
+}
+
+//valuer 数值运算的节点，结果为decimal或null(nil)，时间的加减结果为时间或时间间隔
+type valuer interface {
+	value(Getter) (interface{}, error)
+}
+
+//nodeValue 计算节点的值，数值运算的结果直接返回decimal，避免转换为Number后再解析
+func nodeValue(n InterfaceNoder, getter Getter) (interface{}, error) {
+	switch node := n.(type) {
+	case *NodeNumber:
+		return node.d, nil
+	case valuer:
+		return node.value(getter)
+	}
+	return n.Interface(getter)
+}
+
+//numberResult 将运算结果转换为decimal，结果为null时返回错误
+func numberResult(v interface{}, err error) (decimal.Decimal, error) {
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return toNumber(v)
+}
+
+//interfaceResult 将运算结果中的decimal转换为Number
+func interfaceResult(v interface{}, err error) (interface{}, error) {
+	if d, ok := v.(decimal.Decimal); ok {
+		return numberValue(d), err
+	}
+	return v, err
+}
+
+//numberOperands 计算两个数值操作数，有一个为null时null为true
+func numberOperands(left, right NumberNoder, getter Getter) (x, y decimal.Decimal, null bool, err error) {
+	a, err := nodeValue(left, getter)
+	if err != nil || a == nil {
+		return x, y, a == nil, err
+	}
+	b, err := nodeValue(right, getter)
+	if err != nil || b == nil {
+		return x, y, b == nil, err
+	}
+	if x, err = toNumber(a); err != nil {
+		return x, y, false, err
+	}
+	y, err = toNumber(b)
+	return x, y, false, err
+}
+
+//additive 计算a + b(sign为1)或a - b(sign为-1)，数值的结果为decimal，
+//时间和时间间隔的结果为对应的值，有一边为null时结果为null
+func additive(left, right InterfaceNoder, sign int, getter Getter) (interface{}, error) {
+	a, err := nodeValue(left, getter)
+	if err != nil {
+		return nil, err
+	}
+	b, err := nodeValue(right, getter)
+	if err != nil {
+		return nil, err
+	}
+	if a == nil || b == nil {
+		return nil, nil
+	}
+	if v, ok, err := addTemporal(a, b, sign, getterLocation(getter)); ok {
+		return v, err
+	}
+	x, err := toNumber(a)
+	if err != nil {
+		return nil, err
+	}
+	y, err := toNumber(b)
+	if err != nil {
+		return nil, err
+	}
+	if sign < 0 {
+		return x.Sub(y), nil
+	}
+	return x.Add(y), nil
+}
+
+//between 计算value是否在[low, high]之间，任意一个值为null时null为true
+func between(value, low, high InterfaceNoder, getter Getter) (ok bool, null bool, err error) {
+	values := make([]interface{}, 3)
+	for i, n := range []InterfaceNoder{value, low, high} {
+		values[i], err = nodeValue(n, getter)
+		if err != nil {
+			return false, false, err
 		}
-		return &NodeIn{
-			Key:   tokens[0].Str,
-			Slice: data,
-		}, nil
-	}
-	// not in
-	if len(tokens) >= 6 && strings.ToLower(tokens[1].Str) == KeywordNot && strings.ToLower(tokens[2].Str) == KeywordIn && isLeftParen(tokens[3]) && isRightParen(tokens[len(tokens)-1]) {
-		data := make([]interface{}, 0)
-		for j := 4; j < len(tokens)-1; j++ {
-			data = append(data, tokens[j].Str)
+		if values[i] == nil {
+			return false, true, nil
 		}
-		return &NodeNotIn{
-			Key:   tokens[0].Str,
-			Slice: data,
-		}, nil
 	}
-	return nil, nil
+	loc := getterLocation(getter)
+	cmpLow, ok := compareOrdered(values[0], values[1], loc)
+	if !ok {
+		return false, false, nil
+	}
+	cmpHigh, ok := compareOrdered(values[0], values[2], loc)
+	return ok && cmpLow >= 0 && cmpHigh <= 0, false, nil
+}
+
+//NodeBetween x between low and high，包含两端的值
+type NodeBetween struct {
+	Value InterfaceNoder
+	Low   InterfaceNoder
+	High  InterfaceNoder
+}
+
+func (n NodeBetween) Type() NodeType {
+	return NodeTypeBetween
+}
+
+func (n NodeBetween) Children() []Noder {
+	return []Noder{n.Value, n.Low, n.High}
+}
+
+func (n NodeBetween) Bool(getter Getter) (bool, error) {
+	return isTrue(n.logic(getter))
+}
+
+func (n NodeBetween) logic(getter Getter) (bool, bool, error) {
+	return between(n.Value, n.Low, n.High, getter)
+}
+
+//NodeNotBetween x not between low and high，x为null时为false
+type NodeNotBetween struct {
+	Value InterfaceNoder
+	Low   InterfaceNoder
+	High  InterfaceNoder
+}
+
+func (n NodeNotBetween) Type() NodeType {
+	return NodeTypeNotBetween
+}
+
+func (n NodeNotBetween) Children() []Noder {
+	return []Noder{n.Value, n.Low, n.High}
+}
+
+func (n NodeNotBetween) Bool(getter Getter) (bool, error) {
+	return isTrue(n.logic(getter))
 }
 
-func isAnd(t *Token) bool {
-	return t.Type == TokenTypeUnknow && strings.ToLower(t.Str) == "and"
+func (n NodeNotBetween) logic(getter Getter) (bool, bool, error) {
+	ok, null, err := between(n.Value, n.Low, n.High, getter)
+	return !ok && !null, null, err
 }
 
-func isOr(t *Token) bool {
-	return t.Type == TokenTypeUnknow && strings.ToLower(t.Str) == "or"
+//NodeRegexp x regexp 'pattern'，x不是字符串时为false
+type NodeRegexp struct {
+	Value  InterfaceNoder
+	Regexp *regexp.Regexp
 }
 
-func isArithmeticOperator(t *Token) bool {
-	if t.Type != TokenTypeOperator {
-		return false
+func (n NodeRegexp) Type() NodeType {
+	return NodeTypeRegexp
+}
+
+func (n NodeRegexp) Children() []Noder {
+	return []Noder{n.Value}
+}
+
+func (n NodeRegexp) Bool(getter Getter) (bool, error) {
+	return isTrue(n.logic(getter))
+}
+
+func (n NodeRegexp) logic(getter Getter) (bool, bool, error) {
+	data, err := n.Value.Interface(getter)
+	if err != nil || data == nil {
+		return false, data == nil, err
 	}
-	switch t.Str {
-	case OperatorPlus, OperatorMinus, OperatorMult, OperatorDiv, OperatorMod:
-		return true
+	str, ok := data.(string)
+	if !ok {
+		return false, false, nil
 	}
-	return false
+	return n.Regexp.MatchString(str), false, nil
+}
+
+//NodeNotRegexp x not regexp 'pattern'，x不是字符串时为false
+type NodeNotRegexp struct {
+	Value  InterfaceNoder
+	Regexp *regexp.Regexp
 }
 
-func isLogicalOperator(t *Token) bool {
-	if t.Type != TokenTypeOperator {
-		return false
+func (n NodeNotRegexp) Type() NodeType {
+	return NodeTypeNotRegexp
+}
+
+func (n NodeNotRegexp) Children() []Noder {
+	return []Noder{n.Value}
+}
+
+func (n NodeNotRegexp) Bool(getter Getter) (bool, error) {
+	return isTrue(n.logic(getter))
+}
+
+func (n NodeNotRegexp) logic(getter Getter) (bool, bool, error) {
+	data, err := n.Value.Interface(getter)
+	if err != nil || data == nil {
+		return false, data == nil, err
 	}
-	switch t.Str {
-	case "=", "<>", "!=", "<", ">", "<=", ">=":
-		return true
+	str, ok := data.(string)
+	if !ok {
+		return false, false, nil
 	}
-	return false
+	return !n.Regexp.MatchString(str), false, nil
 }
@@ -0,0 +1,232 @@
+package json_filter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+//boolCase 条件和期望的结果
+type boolCase struct {
+	cond string
+	want bool
+}
+
+//checkBools 对getter计算每个条件
+func checkBools(t *testing.T, getter Getter, cases []boolCase) {
+	t.Helper()
+	for _, c := range cases {
+		if got := evalBool(t, c.cond, getter); got != c.want {
+			t.Errorf("%s = %v, want %v", c.cond, got, c.want)
+		}
+	}
+}
+
+func TestNot(t *testing.T) {
+	getter := mapGetter{"level": "info", "enabled": true, "off": false}
+	checkBools(t, getter, []boolCase{
+		{"not (level='info' or level='debug')", false},
+		{"not level = 'debug'", true},
+		{"not not level = 'info'", true},
+		{"enabled", true},
+		{"not enabled", false},
+		{"off", false},
+		{"enabled and not off", true},
+		{"enabled = true", true},
+		{"true", true},
+		{"not false and true", true},
+		{"false or not true", false},
+		{"enabled is true", true},
+		{"enabled is not true", false},
+		{"off is false", true},
+		{"off is true", false},
+		{"off is not false", false},
+		//不存在的字段既不是true也不是false
+		{"none is true", false},
+		{"none is not true", true},
+		{"none is false", false},
+		{"none is not false", true},
+	})
+}
+
+//TestBoolField json中的布尔值可以直接作为条件
+func TestBoolField(t *testing.T) {
+	input := `{"id":1,"data":{"enabled":true}}
+{"id":2,"data":{"enabled":false}}
+{"id":3,"data":{}}
+{"id":4,"data":{"enabled":"yes"}}
+`
+	cases := []struct {
+		sql  string
+		rows []string
+	}{
+		{"select id from t where data.enabled", []string{`{"id":1}`}},
+		{"select id from t where not data.enabled", []string{`{"id":2}`}},
+		{"select id from t where data.enabled is not true", []string{`{"id":2}`, `{"id":3}`, `{"id":4}`}},
+		{"select id, if(data.enabled, 1, 0) as on from t where id < 3", []string{`{"id":1,"on":1}`, `{"id":2,"on":0}`}},
+	}
+	for _, c := range cases {
+		rows, _ := runSQL(t, c.sql, input)
+		if !reflect.DeepEqual(rows, c.rows) {
+			t.Errorf("%s: rows %q, want %q", c.sql, rows, c.rows)
+		}
+	}
+}
+
+func TestBetween(t *testing.T) {
+	ts, err := time.Parse(time.RFC3339, "2024-01-02T10:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	getter := mapGetter{"n": Number("5"), "s": "b", "ts": ts, "tstr": "2024-01-02T10:00:00Z"}
+	checkBools(t, getter, []boolCase{
+		{"n between 1 and 5", true},
+		{"n between 5 and 9", true},
+		{"n between 6 and 10", false},
+		{"n not between 6 and 10", true},
+		{"n not between 1 and 10", false},
+		{"n between 5 and 1", false},
+		{"n between '1' and '9'", true},
+		{"n between 1 and 5 and s = 'b'", true},
+		{"s = 'x' or n between 1 and 2 or s between 'a' and 'c'", true},
+		{"s between 'a' and 'c'", true},
+		{"s between 'c' and 'z'", false},
+		{"ts between '2024-01-01' and '2024-01-03'", true},
+		{"ts between '2024-01-02T10:00:01Z' and '2024-01-03'", false},
+		{"tstr between '2024-01-02T09:00:00Z' and '2024-01-02T11:00:00Z'", true},
+		{"none between 1 and 2", false},
+		{"none not between 1 and 2", false},
+	})
+}
+
+func TestIn(t *testing.T) {
+	getter := mapGetter{
+		"code":  Number("502"),
+		"codes": "502",
+		"n":     Number("5"),
+		"tags":  []interface{}{"x", "y", Number("3")},
+	}
+	checkBools(t, getter, []boolCase{
+		{"code in (500, 502)", true},
+		{"code in (500, 501)", false},
+		{"code in ('502')", true},
+		{"codes in (502)", true},
+		{"code not in (500, 501)", true},
+		{"code not in (500, 502)", false},
+		{"code in (500, 500 + 2)", true},
+		{"n in (n + 0, 1)", true},
+		{"'x' in tags", true},
+		{"3 in tags", true},
+		{"'3' in tags", true},
+		{"'z' in tags", false},
+		{"'z' not in tags", true},
+		{"'x' not in tags", false},
+		//null和不存在的字段与任何值比较都不成立
+		{"none in (1)", false},
+		{"none not in (1)", false},
+		{"n in (1, null)", false},
+		{"n not in (1, null)", false},
+		{"n in (5, null)", true},
+	})
+}
+
+func TestRegexp(t *testing.T) {
+	getter := mapGetter{"path": "/api/v1/users/42", "n": Number("42")}
+	checkBools(t, getter, []boolCase{
+		{"path regexp '^/api/v[0-9]+/users/[0-9]+$'", true},
+		{"path regexp '^/users'", false},
+		{"path rlike 'users'", true},
+		{"path not regexp 'admin'", true},
+		{"path not rlike 'users'", false},
+		{"path ~ 'v1'", true},
+		{"path ~ 'V1'", false},
+		{"path ~* 'V1'", true},
+		{"path !~ 'admin'", true},
+		{"path !~* 'API'", false},
+		{`path ~ '\d+$'`, true},
+		//不是字符串时不匹配
+		{"n regexp '^4'", false},
+		{"none regexp 'x'", false},
+		{"none not regexp 'x'", false},
+	})
+	values := []struct {
+		expr string
+		want interface{}
+	}{
+		{"regexp_extract(path, '/users/([0-9]+)', 1)", "42"},
+		{"regexp_extract(path, '/users/([0-9]+)')", "/users/42"},
+		{"regexp_extract(path, '/(v)([0-9])', 2)", "1"},
+		{"regexp_extract(path, 'x(y)', 1)", nil},
+		{"regexp_replace(path, '[0-9]+', 'N')", "/api/vN/users/N"},
+		{"regexp_replace(path, '/users/([0-9]+)', '/u/$1')", "/api/v1/u/42"},
+	}
+	for _, c := range values {
+		if got := evalValue(t, c.expr, getter); got != c.want {
+			t.Errorf("%s = %#v, want %#v", c.expr, got, c.want)
+		}
+	}
+	//正则表达式在解析sql时编译，错误时指出位置
+	_, err := ParseStatement("select * from t where path regexp '('")
+	if se, ok := err.(*SyntaxError); !ok || se.Column != 35 {
+		t.Errorf("invalid regexp: got %v", err)
+	}
+}
+
+//TestNullLogic 三值逻辑，null和不存在的字段都当作null，is missing区分两者
+func TestNullLogic(t *testing.T) {
+	input := `{"id":1,"a":1,"data":{"title":"x"}}
+{"id":2,"a":null,"data":{"title":null}}
+{"id":3,"data":{}}
+{"id":4,"a":2}
+`
+	cases := []struct {
+		where string
+		ids   string
+	}{
+		{"a <> 1", "4"},
+		{"a <> 1 or a is null", "2,3,4"},
+		{"a is null", "2,3"},
+		{"a is not null", "1,4"},
+		{"a is missing", "3"},
+		{"a is not missing", "1,2,4"},
+		{"exists(a)", "1,2,4"},
+		{"exists('data.title')", "1,2"},
+		{"data.title is not missing and data.title is null", "2"},
+		{"not a = 1", "4"},
+		//is比not优先
+		{"not (a = 1) is not true", "1"},
+		{"(a > 1) is not true", "1,2,3"},
+		{"a = 1 or true", "1,2,3,4"},
+		{"a = 1 and false", ""},
+		{"(a = 1 and false) is false", "1,2,3,4"},
+		{"a + 1 is null", "2,3"},
+		{"a in (2, null)", "4"},
+		{"a not in (2, null)", ""},
+		{"a not in (2)", "1"},
+		{"a = null", ""},
+		{"null is null", "1,2,3,4"},
+		{"coalesce(a, 0) = 0", "2,3"},
+	}
+	for _, c := range cases {
+		rows, errs := runSQL(t, "select id from t where "+c.where, input)
+		ids := make([]string, len(rows))
+		for i, row := range rows {
+			ids[i] = strings.TrimSuffix(strings.TrimPrefix(row, `{"id":`), "}")
+		}
+		if got := strings.Join(ids, ","); got != c.ids || len(errs) != 0 {
+			t.Errorf("%s: ids %s errors %q, want %s", c.where, got, errs, c.ids)
+		}
+	}
+	//输出时不存在的字段为null
+	rows, _ := runSQL(t, "select id, a, data.title from t", input)
+	want := []string{
+		`{"id":1,"a":1,"data.title":"x"}`,
+		`{"id":2,"a":null,"data.title":null}`,
+		`{"id":3,"a":null,"data.title":null}`,
+		`{"id":4,"a":2,"data.title":null}`,
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows %q, want %q", rows, want)
+	}
+}
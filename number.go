@@ -0,0 +1,46 @@
+package json_filter
+
+import (
+	stdjson "encoding/json"
+	"fmt"
+	"strings"
+
+	json "github.com/json-iterator/go"
+	"github.com/shopspring/decimal"
+)
+
+//Number 精确的数值，保存数值的十进制文本，计算和比较时转换为decimal，大整数和小数都不会丢失精度
+type Number = stdjson.Number
+
+//exactJSON 解析json时数字解析为Number而不是float64
+var exactJSON = json.Config{UseNumber: true}.Froze()
+
+//numberValue 将计算结果转换为Number
+func numberValue(d decimal.Decimal) Number {
+	return Number(d.String())
+}
+
+//isNumberValue 判断是否为数值
+func isNumberValue(data interface{}) bool {
+	switch data.(type) {
+	case Number, decimal.Decimal, float64:
+		return true
+	}
+	return false
+}
+
+//toNumber 将数值或数字字符串转换为decimal
+func toNumber(data interface{}) (decimal.Decimal, error) {
+	switch v := data.(type) {
+	case Number:
+		return decimal.NewFromString(string(v))
+	case decimal.Decimal:
+		return v, nil
+	case float64:
+		return decimal.NewFromFloat(v), nil
+	case string:
+		return decimal.NewFromString(strings.TrimSpace(v))
+	default:
+		return decimal.Zero, fmt.Errorf("unsupported data type")
+	}
+}
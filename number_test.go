@@ -0,0 +1,73 @@
+package json_filter
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+//TestExactNumbers 大整数和小数按十进制精确计算和比较，不经过float64
+func TestExactNumbers(t *testing.T) {
+	getter := mapGetter{
+		"id":  Number("1234567890123456789"),
+		"p":   Number("0.1"),
+		"q":   Number("0.2"),
+		"big": Number("123456789012345678901234567890"),
+		"f":   Number("1.50"),
+		"s":   "1.5",
+	}
+	values := []struct {
+		expr string
+		want string
+	}{
+		{"id + 1", "1234567890123456790"},
+		{"id - 1234567890123456780", "9"},
+		{"p + q", "0.3"},
+		{"big * 2", "246913578024691357802469135780"},
+		{"id % 1000", "789"},
+		{"id div 1000", "1234567890123456"},
+		{"f * 2", "3"},
+		{"1e3 + 1", "1001"},
+	}
+	for _, c := range values {
+		if got := fmt.Sprint(evalValue(t, c.expr, getter)); got != c.want {
+			t.Errorf("%s = %s, want %s", c.expr, got, c.want)
+		}
+	}
+	conds := []struct {
+		cond string
+		want bool
+	}{
+		{"id = 1234567890123456789", true},
+		{"id = 1234567890123456788", false},
+		{"id > 1234567890123456788", true},
+		{"id < 1234567890123456789", false},
+		{"p + q = 0.3", true},
+		{"f = 1.5", true},
+		{"f = s", true},
+		{"s = 1.50", true},
+		{"big > id", true},
+		{"id in (1234567890123456788, 1234567890123456789)", true},
+	}
+	for _, c := range conds {
+		if got := evalBool(t, c.cond, getter); got != c.want {
+			t.Errorf("%s = %v, want %v", c.cond, got, c.want)
+		}
+	}
+}
+
+//TestExactNumbersFromJSON 从json中取出的数字保持原来的精度
+func TestExactNumbersFromJSON(t *testing.T) {
+	input := `{"id":1234567890123456789}
+{"id":1234567890123456788}
+{"id":1234567890123456790}
+`
+	rows, errs := runSQL(t, "select id, id + 1 as next from t where id >= 1234567890123456789 order by id desc", input)
+	want := []string{
+		`{"id":1234567890123456790,"next":1234567890123456791}`,
+		`{"id":1234567890123456789,"next":1234567890123456790}`,
+	}
+	if !reflect.DeepEqual(rows, want) || len(errs) != 0 {
+		t.Errorf("rows %q errors %q, want %q", rows, errs, want)
+	}
+}
@@ -0,0 +1,205 @@
+package json_filter
+
+import (
+	"fmt"
+	"strings"
+
+	json "github.com/json-iterator/go"
+)
+
+//RawGetter 可以直接返回字段原始json的Getter，用于原样输出字段的值
+type RawGetter interface {
+	//GetRaw 返回字段原始的json，字段不存在时返回nil，ok为false表示无法取得原始json
+	GetRaw(string) (raw []byte, ok bool)
+}
+
+//getRawFromJSON 按key从json中取出原始的json，不经过解码再编码，保持数字的精度和格式
+func getRawFromJSON(data []byte, key string) ([]byte, bool) {
+	if key == "[keys]" {
+		return nil, false
+	}
+	iter := json.ConfigDefault.BorrowIterator(data)
+	defer json.ConfigDefault.ReturnIterator(iter)
+	segments := parsePath(key)
+	if !isPlainPath(segments) {
+		return nil, false
+	}
+	for _, seg := range segments {
+		found := false
+		switch {
+		case seg.kind == segmentIndex && iter.WhatIsNext() == json.ArrayValue:
+			i := 0
+			iter.ReadArrayCB(func(iter *json.Iterator) bool {
+				if i == seg.index {
+					found = true
+					return false
+				}
+				i++
+				iter.Skip()
+				return true
+			})
+		case seg.kind == segmentKey && iter.WhatIsNext() == json.ObjectValue:
+			iter.ReadObjectCB(func(iter *json.Iterator, field string) bool {
+				if field == seg.key {
+					found = true
+					return false
+				}
+				iter.Skip()
+				return true
+			})
+		}
+		if !found || iter.Error != nil {
+			return nil, true
+		}
+	}
+	raw := iter.SkipAndReturnBytes()
+	if iter.Error != nil {
+		return nil, true
+	}
+	return raw, true
+}
+
+//outputField 输出的一个字段
+type outputField struct {
+	item SelectItem
+	//path 输出嵌套结构时字段所在的路径
+	path []string
+}
+
+//outputNode 输出的json的结构，叶子节点对应一个字段
+type outputNode struct {
+	key      string
+	field    int
+	children []*outputNode
+}
+
+func (n *outputNode) child(key string) *outputNode {
+	for _, c := range n.children {
+		if c.key == key {
+			return c
+		}
+	}
+	c := &outputNode{key: key, field: -1}
+	n.children = append(n.children, c)
+	return c
+}
+
+//rowWriter 按select的字段列表生成一行json，字段的顺序与select中的顺序一致
+type rowWriter struct {
+	fields []outputField
+	root   *outputNode
+	nested bool
+}
+
+//outputPath 字段和别名按.拆分为多级，其他表达式作为一个整体
+func outputPath(item SelectItem) []string {
+	if item.Alias != "" {
+		return strings.Split(item.Name, ".")
+	}
+	if n, ok := item.Expr.(*NodeField); ok {
+		return splitPath(n.key)
+	}
+	return []string{item.Name}
+}
+
+func newRowWriter(items []SelectItem, nested bool) (*rowWriter, error) {
+	w := &rowWriter{
+		fields: make([]outputField, 0, len(items)),
+		root:   &outputNode{field: -1},
+		nested: nested,
+	}
+	for _, item := range items {
+		if item.Name == "*" {
+			continue
+		}
+		field := outputField{
+			item: item,
+			path: []string{item.Name},
+		}
+		if nested {
+			field.path = outputPath(item)
+		}
+		//不嵌套输出时只有名字相同的字段冲突
+		for _, other := range w.fields {
+			if isPathPrefix(other.path, field.path) || isPathPrefix(field.path, other.path) {
+				if !nested {
+					return nil, fmt.Errorf("duplicate output field %s", item.Name)
+				}
+				return nil, fmt.Errorf("output fields %s and %s conflict", other.item.Name, item.Name)
+			}
+		}
+		node := w.root
+		for _, key := range field.path {
+			node = node.child(key)
+		}
+		node.field = len(w.fields)
+		w.fields = append(w.fields, field)
+	}
+	return w, nil
+}
+
+//isPathPrefix 判断a是否为b的前缀
+func isPathPrefix(a, b []string) bool {
+	if len(a) > len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+//Write 计算各个字段的值并生成json
+func (w *rowWriter) Write(getter Getter) ([]byte, error) {
+	stream := sortedJSON.BorrowStream(nil)
+	defer sortedJSON.ReturnStream(stream)
+	if err := w.writeNode(stream, w.root, getter); err != nil {
+		return nil, err
+	}
+	if stream.Error != nil {
+		return nil, stream.Error
+	}
+	return append([]byte(nil), stream.Buffer()...), nil
+}
+
+func (w *rowWriter) writeNode(stream *json.Stream, node *outputNode, getter Getter) error {
+	if node.field >= 0 {
+		return w.writeField(stream, w.fields[node.field], getter)
+	}
+	stream.WriteObjectStart()
+	for i, child := range node.children {
+		if i > 0 {
+			stream.WriteMore()
+		}
+		stream.WriteObjectField(child.key)
+		if err := w.writeNode(stream, child, getter); err != nil {
+			return err
+		}
+	}
+	stream.WriteObjectEnd()
+	return nil
+}
+
+//writeField 输出字段的值，直接引用的字段原样输出原始json
+func (w *rowWriter) writeField(stream *json.Stream, field outputField, getter Getter) error {
+	if n, ok := field.item.Expr.(*NodeField); ok {
+		if rawGetter, ok := getter.(RawGetter); ok {
+			if raw, ok := rawGetter.GetRaw(n.key); ok {
+				if raw == nil {
+					stream.WriteNil()
+				} else {
+					stream.Write(raw)
+				}
+				return nil
+			}
+		}
+	}
+	data, err := field.item.Expr.Interface(getter)
+	if err != nil {
+		return err
+	}
+	stream.WriteVal(data)
+	return nil
+}
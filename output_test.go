@@ -0,0 +1,92 @@
+package json_filter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const outputInput = `{"ts":1700000000,"msg":"hi","data":{"title":"hello","n":1.0,"id":1234567890123456789,"tags":["a", "b"]},"z":1e3}
+`
+
+//TestSelectExpressions select中可以是表达式，用别名或表达式的sql文本作为输出的字段名
+func TestSelectExpressions(t *testing.T) {
+	cases := []struct {
+		sql  string
+		rows []string
+	}{
+		{"select ts*1000 as ms, data.title as title, msg from t", []string{`{"ms":1700000000000,"title":"hello","msg":"hi"}`}},
+		{"select ts + 1, upper(msg), data.n * 2 as d from t", []string{`{"ts + 1":1700000001,"upper(msg)":"HI","d":2}`}},
+		{"select msg as 'my msg', (ts - 1700000000) * 2 as \"x.y\" from t", []string{`{"my msg":"hi","x.y":0}`}},
+		{"select msg || '!' as m from t where ts > 0", []string{`{"m":"hi!"}`}},
+	}
+	for _, c := range cases {
+		rows, errs := runSQL(t, c.sql, outputInput)
+		if !reflect.DeepEqual(rows, c.rows) || len(errs) != 0 {
+			t.Errorf("%s: rows %q errors %q, want %q", c.sql, rows, errs, c.rows)
+		}
+	}
+}
+
+//TestOutputFieldErrors 输出的字段名重复或者嵌套输出时互相冲突时报错
+func TestOutputFieldErrors(t *testing.T) {
+	cases := []struct {
+		sql    string
+		nested bool
+		err    string
+	}{
+		{"select msg, msg from t", false, "duplicate output field msg"},
+		{"select msg as a, data.title as a from t", false, "duplicate output field a"},
+		{"select data, data.title from t", true, "output fields data and data.title conflict"},
+		{"select msg as data, data.title from t", true, "output fields data and data.title conflict"},
+		{"select data.title, data.title.x from t", true, "output fields data.title and data.title.x conflict"},
+	}
+	for _, c := range cases {
+		_, err := NewJSONFilterWithConfig(FilterConfig{
+			Reader:       strings.NewReader(outputInput),
+			SQL:          c.sql,
+			NestedOutput: c.nested,
+		})
+		if err == nil || err.Error() != c.err {
+			t.Errorf("%s: got error %v, want %s", c.sql, err, c.err)
+		}
+	}
+}
+
+func TestNestedOutput(t *testing.T) {
+	cases := []struct {
+		sql  string
+		rows []string
+	}{
+		{"select msg, data.title, data.n from t", []string{`{"msg":"hi","data":{"title":"hello","n":1.0}}`}},
+		{"select data.title as 'a.b', data.n as \"a.c\", ts from t", []string{`{"a":{"b":"hello","c":1.0},"ts":1700000000}`}},
+		{"select data.tags[0] as 'x.first', upper(data.title) as x.title from t", []string{`{"x":{"first":"a","title":"HELLO"}}`}},
+		{"select data.title, msg, data.id from t", []string{`{"data":{"title":"hello","id":1234567890123456789},"msg":"hi"}`}},
+	}
+	for _, c := range cases {
+		rows, errs := runQuery(t, FilterConfig{Reader: strings.NewReader(outputInput), SQL: c.sql, NestedOutput: true})
+		if !reflect.DeepEqual(rows, c.rows) || len(errs) != 0 {
+			t.Errorf("%s: rows %q errors %q, want %q", c.sql, rows, errs, c.rows)
+		}
+	}
+}
+
+//TestRawOutput 按select的顺序输出字段，字段的值原样复制，数字的写法不变
+func TestRawOutput(t *testing.T) {
+	cases := []struct {
+		sql  string
+		rows []string
+	}{
+		{"select z, msg, ts from t", []string{`{"z":1e3,"msg":"hi","ts":1700000000}`}},
+		{"select data.n, data.id from t", []string{`{"data.n":1.0,"data.id":1234567890123456789}`}},
+		{"select data.tags, data.id + 1 as next from t", []string{`{"data.tags":["a", "b"],"next":1234567890123456790}`}},
+		{"select data from t", []string{`{"data":{"title":"hello","n":1.0,"id":1234567890123456789,"tags":["a", "b"]}}`}},
+		{"select * from t", []string{strings.TrimSpace(outputInput)}},
+	}
+	for _, c := range cases {
+		rows, errs := runSQL(t, c.sql, outputInput)
+		if !reflect.DeepEqual(rows, c.rows) || len(errs) != 0 {
+			t.Errorf("%s: rows %q errors %q, want %q", c.sql, rows, errs, c.rows)
+		}
+	}
+}
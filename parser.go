@@ -0,0 +1,1283 @@
+package json_filter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+)
+
+//SyntaxError sql语法错误，包含出错的位置
+type SyntaxError struct {
+	SQL string
+	//Offset 出错位置在sql中的字节偏移
+	Offset int
+	//Line 出错的行，从1开始
+	Line int
+	//Column 出错的列，从1开始，按字符计算
+	Column int
+	Msg    string
+}
+
+func newSyntaxError(sql string, offset int, format string, a ...interface{}) *SyntaxError {
+	if offset > len(sql) {
+		offset = len(sql)
+	}
+	lineStart := strings.LastIndexByte(sql[:offset], '\n') + 1
+	return &SyntaxError{
+		SQL:    sql,
+		Offset: offset,
+		Line:   strings.Count(sql[:offset], "\n") + 1,
+		Column: utf8.RuneCountInString(sql[lineStart:offset]) + 1,
+		Msg:    fmt.Sprintf(format, a...),
+	}
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("sql syntax error at line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+//Snippet 返回出错的那一行sql，并在下一行用^标出出错的位置
+func (e *SyntaxError) Snippet() string {
+	lineStart := strings.LastIndexByte(e.SQL[:e.Offset], '\n') + 1
+	lineEnd := strings.IndexByte(e.SQL[e.Offset:], '\n')
+	if lineEnd == -1 {
+		lineEnd = len(e.SQL)
+	} else {
+		lineEnd += e.Offset
+	}
+	var caret strings.Builder
+	for _, r := range e.SQL[lineStart:e.Offset] {
+		if r == '\t' {
+			caret.WriteByte('\t')
+		} else {
+			caret.WriteByte(' ')
+		}
+	}
+	caret.WriteByte('^')
+	return e.SQL[lineStart:lineEnd] + "\n" + caret.String()
+}
+
+//aggregateRef 解析过程中遇到的聚合函数及其位置
+type aggregateRef struct {
+	node  *NodeAggregate
+	token *Token
+}
+
+//parser 递归下降的sql解析器，运算符优先级从低到高依次为:
+//or、and、not、比较(= <> < > <= >= is like in)、+ -、* / %
+type parser struct {
+	sql    string
+	tokens []*Token
+	pos    int
+	aggs   []aggregateRef
+	//star select *的位置
+	star *Token
+}
+
+func newParser(sql string, tokens []*Token) *parser {
+	return &parser{
+		sql:    sql,
+		tokens: tokens,
+	}
+}
+
+//peek 返回当前的token，到达结尾时返回nil
+func (p *parser) peek() *Token {
+	if p.pos >= len(p.tokens) {
+		return nil
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() *Token {
+	t := p.peek()
+	if t != nil {
+		p.pos++
+	}
+	return t
+}
+
+//errorAt 在token的位置生成语法错误，token为nil时表示sql的结尾
+func (p *parser) errorAt(t *Token, format string, a ...interface{}) *SyntaxError {
+	offset := len(p.sql)
+	if t != nil {
+		offset = t.Pos
+	}
+	return newSyntaxError(p.sql, offset, format, a...)
+}
+
+//unexpected 当前token不符合预期
+func (p *parser) unexpected(expected string) *SyntaxError {
+	t := p.peek()
+	if t == nil {
+		return p.errorAt(nil, "expected %s, got end of sql", expected)
+	}
+	return p.errorAt(t, "expected %s, got %s", expected, t)
+}
+
+func isKeywordToken(t *Token, keyword string) bool {
+	return t != nil && t.Type == TokenTypeUnknow && strings.ToLower(t.Str) == keyword
+}
+
+func (p *parser) isKeyword(keyword string) bool {
+	return isKeywordToken(p.peek(), keyword)
+}
+
+//acceptKeyword 当前token为指定关键字时跳过并返回true
+func (p *parser) acceptKeyword(keyword string) bool {
+	if p.isKeyword(keyword) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectKeyword(keyword string) error {
+	if !p.acceptKeyword(keyword) {
+		return p.unexpected(keyword)
+	}
+	return nil
+}
+
+func (p *parser) isComma() bool {
+	t := p.peek()
+	return t != nil && isComma(t)
+}
+
+func (p *parser) isOperator(ops ...string) bool {
+	t := p.peek()
+	if t == nil {
+		return false
+	}
+	for _, op := range ops {
+		if t.Type == TokenTypeOperator && t.Str == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expectRightParen() error {
+	t := p.peek()
+	if t == nil || !isRightParen(t) {
+		return p.unexpected(")")
+	}
+	p.pos++
+	return nil
+}
+
+//parseStatements 解析用;分隔的多条语句
+func (p *parser) parseStatements() ([]*Statement, error) {
+	stmts := make([]*Statement, 0)
+	for {
+		for p.isSemicolon() {
+			p.pos++
+		}
+		if p.peek() == nil {
+			return stmts, nil
+		}
+		stmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+	}
+}
+
+func (p *parser) isSemicolon() bool {
+	t := p.peek()
+	return t != nil && t.Type == TokenTypeKeyword && t.Str == ";"
+}
+
+//parseStatement 解析一条select语句，语句以;或sql的结尾结束
+func (p *parser) parseStatement() (*Statement, error) {
+	p.aggs = nil
+	p.star = nil
+	stmt := &Statement{
+		Fields:  make([]string, 0),
+		Checker: NodeTrue{},
+		Limit:   -1,
+	}
+	if err := p.expectKeyword("select"); err != nil {
+		return nil, err
+	}
+	stmt.Distinct = p.acceptKeyword("distinct")
+	items, err := p.parseSelectItems()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Items = items
+	for _, item := range items {
+		stmt.Fields = append(stmt.Fields, item.Name)
+	}
+	if err := p.expectKeyword("from"); err != nil {
+		return nil, err
+	}
+	if !p.acceptKeyword("t") {
+		return nil, p.unexpected("table name t")
+	}
+	if p.acceptKeyword("where") {
+		aggCount := len(p.aggs)
+		checker, err := p.parseBool()
+		if err != nil {
+			return nil, err
+		}
+		if len(p.aggs) > aggCount {
+			return nil, p.errorAt(p.aggs[aggCount].token, "aggregate functions are not allowed in where")
+		}
+		stmt.Checker = checker
+	}
+	if p.acceptKeyword("group") {
+		if err := p.expectKeyword("by"); err != nil {
+			return nil, err
+		}
+		aggCount := len(p.aggs)
+		stmt.GroupBy, err = p.parseValueList()
+		if err != nil {
+			return nil, err
+		}
+		if len(p.aggs) > aggCount {
+			return nil, p.errorAt(p.aggs[aggCount].token, "aggregate functions are not allowed in group by")
+		}
+	}
+	if p.acceptKeyword("having") {
+		stmt.Having, err = p.parseBool()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("order") {
+		if err := p.expectKeyword("by"); err != nil {
+			return nil, err
+		}
+		stmt.OrderBy, err = p.parseOrderBy()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("limit") {
+		stmt.Limit, err = p.parseCount()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("offset") {
+		stmt.Offset, err = p.parseCount()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if t := p.peek(); t != nil && !p.isSemicolon() {
+		return nil, p.errorAt(t, "unexpected %s", t)
+	}
+	for _, ref := range p.aggs {
+		stmt.Aggregates = append(stmt.Aggregates, ref.node)
+	}
+	if stmt.Grouped() && p.star != nil {
+		return nil, p.errorAt(p.star, "select * is not supported with group by")
+	}
+	return stmt, nil
+}
+
+//parseSelectItems 解析select和from之间的字段列表
+func (p *parser) parseSelectItems() ([]SelectItem, error) {
+	items := make([]SelectItem, 0)
+	for {
+		item := SelectItem{}
+		start := p.pos
+		if t := p.peek(); t != nil && t.Str == "*" && t.Type != TokenTypeString {
+			p.pos++
+			p.star = t
+			item.Expr = &NodeField{key: "*"}
+		} else {
+			expr, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			item.Expr = expr
+		}
+		item.Name = tokensText(p.tokens[start:p.pos])
+		if field, ok := item.Expr.(*NodeField); ok {
+			//带引号的字段名输出时去掉引号
+			item.Name = formatPath(parsePath(field.key), false)
+		}
+		if p.acceptKeyword("as") {
+			alias := p.peek()
+			switch {
+			case alias == nil:
+				return nil, p.unexpected("alias")
+			case alias.Type == TokenTypeString:
+				item.Alias = alias.Str
+			case alias.Type == TokenTypeIdentifier || alias.Type == TokenTypeUnknow && !isReserved(strings.ToLower(alias.Str)):
+				item.Alias = formatPath(parsePath(alias.Str), false)
+			default:
+				return nil, p.unexpected("alias")
+			}
+			p.pos++
+			item.Name = item.Alias
+		}
+		items = append(items, item)
+		if !p.isComma() {
+			return items, nil
+		}
+		p.pos++
+	}
+}
+
+//parseValueList 解析逗号分隔的表达式列表
+func (p *parser) parseValueList() ([]InterfaceNoder, error) {
+	exprs := make([]InterfaceNoder, 0)
+	for {
+		expr, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+		if !p.isComma() {
+			return exprs, nil
+		}
+		p.pos++
+	}
+}
+
+//parseOrderBy 解析order by子句
+func (p *parser) parseOrderBy() ([]OrderByItem, error) {
+	items := make([]OrderByItem, 0)
+	for {
+		expr, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		item := OrderByItem{Expr: expr}
+		if p.acceptKeyword("desc") {
+			item.Desc = true
+		} else {
+			p.acceptKeyword("asc")
+		}
+		//默认null为最小值
+		item.NullsFirst = !item.Desc
+		if p.acceptKeyword("nulls") {
+			switch {
+			case p.acceptKeyword("first"):
+				item.NullsFirst = true
+			case p.acceptKeyword("last"):
+				item.NullsFirst = false
+			default:
+				return nil, p.unexpected("first or last")
+			}
+		}
+		items = append(items, item)
+		if !p.isComma() {
+			return items, nil
+		}
+		p.pos++
+	}
+}
+
+//parseCount 解析limit、offset后的非负整数
+func (p *parser) parseCount() (int, error) {
+	t := p.peek()
+	if t == nil || t.Type != TokenTypeNumber {
+		return 0, p.unexpected("a number")
+	}
+	n, err := strconv.Atoi(t.Str)
+	if err != nil || n < 0 {
+		return 0, p.errorAt(t, "expected a non-negative integer, got %s", t)
+	}
+	p.pos++
+	return n, nil
+}
+
+//parseBool 解析结果为真假的表达式
+func (p *parser) parseBool() (BoolNoder, error) {
+	start := p.peek()
+	n, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return p.asBool(n, start)
+}
+
+//parseValue 解析有值的表达式
+func (p *parser) parseValue() (InterfaceNoder, error) {
+	start := p.peek()
+	n, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	v, ok := n.(InterfaceNoder)
+	if !ok {
+		return nil, p.errorAt(start, "expected a value expression")
+	}
+	return v, nil
+}
+
+func (p *parser) parseExpr() (Noder, error) {
+	return p.parseOr()
+}
+
+//asBool 检查操作数是否为真假值，作为条件的表达式用到可以匹配多个值的字段时有一个值满足条件即成立，
+//如starts_with(data.*.name, 'x')
+func (p *parser) asBool(n Noder, at *Token) (BoolNoder, error) {
+	b, ok := n.(BoolNoder)
+	if !ok {
+		return nil, p.errorAt(at, "expected a boolean expression")
+	}
+	return anyMatch(b), nil
+}
+
+//asValue 检查操作数是否有值
+func (p *parser) asValue(n Noder, at *Token) (InterfaceNoder, error) {
+	v, ok := n.(InterfaceNoder)
+	if !ok {
+		return nil, p.errorAt(at, "expected a value expression")
+	}
+	return v, nil
+}
+
+//asNumber 检查操作数是否为数值
+func (p *parser) asNumber(n Noder, at *Token) (NumberNoder, error) {
+	num, ok := n.(NumberNoder)
+	if !ok {
+		return nil, p.errorAt(at, "expected a number expression")
+	}
+	return num, nil
+}
+
+func (p *parser) parseOr() (Noder, error) {
+	start := p.peek()
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword(KeywordOr) {
+		p.pos++
+		rightStart := p.peek()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		leftB, err := p.asBool(left, start)
+		if err != nil {
+			return nil, err
+		}
+		rightB, err := p.asBool(right, rightStart)
+		if err != nil {
+			return nil, err
+		}
+		left = &NodeOr{
+			Left:  leftB,
+			Right: rightB,
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Noder, error) {
+	start := p.peek()
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword(KeywordAnd) {
+		p.pos++
+		rightStart := p.peek()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		leftB, err := p.asBool(left, start)
+		if err != nil {
+			return nil, err
+		}
+		rightB, err := p.asBool(right, rightStart)
+		if err != nil {
+			return nil, err
+		}
+		left = &NodeAnd{
+			Left:  leftB,
+			Right: rightB,
+		}
+	}
+	return left, nil
+}
+
+//parseNot 解析一元的not
+func (p *parser) parseNot() (Noder, error) {
+	if !p.isKeyword(KeywordNot) {
+		return p.parsePredicate()
+	}
+	p.pos++
+	start := p.peek()
+	n, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	b, err := p.asBool(n, start)
+	if err != nil {
+		return nil, err
+	}
+	return &NodeNot{Node: b}, nil
+}
+
+//parsePredicate 解析比较运算以及is null、like、in，用到可以匹配多个值的字段时有一个值满足条件即成立
+func (p *parser) parsePredicate() (Noder, error) {
+	start := p.peek()
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	n, err := p.parsePredicateOperator(left, start)
+	if err != nil || n == left {
+		return n, err
+	}
+	if b, ok := n.(BoolNoder); ok {
+		return anyMatch(b), nil
+	}
+	return n, nil
+}
+
+//parsePredicateOperator 解析left后面的比较运算，没有比较运算时返回left
+func (p *parser) parsePredicateOperator(left Noder, start *Token) (Noder, error) {
+	switch {
+	case p.isOperator(OperatorEqual, OperatorNotEqual, OperatorNotEqual2, OperatorLessThan, OperatorLessEqual, OperatorGreaterThan, OperatorGreaterEqual):
+		return p.parseComparison(left, start)
+	case p.isOperator(OperatorRegexp, OperatorIRegexp, OperatorNotRegexp, OperatorNotIRegexp):
+		op := p.next()
+		not := op.Str == OperatorNotRegexp || op.Str == OperatorNotIRegexp
+		ignoreCase := op.Str == OperatorIRegexp || op.Str == OperatorNotIRegexp
+		return p.parseRegexp(left, start, not, ignoreCase)
+	case p.isKeyword(KeywordIs):
+		p.pos++
+		return p.parseIs(left, start)
+	}
+	//between、like、in、regexp前面可以加not
+	not := false
+	if p.isKeyword(KeywordNot) && p.pos+1 < len(p.tokens) && isNegatableKeyword(p.tokens[p.pos+1]) {
+		p.pos++
+		not = true
+	}
+	switch {
+	case p.isKeyword(KeywordBetween):
+		return p.parseBetween(left, start, not)
+	case p.isKeyword(KeywordLike) || p.isKeyword(KeywordILike) || p.isKeyword(KeywordIn):
+		return p.parseMembership(left, start, not)
+	case p.isKeyword(KeywordRegexp) || p.isKeyword(KeywordRlike):
+		p.pos++
+		return p.parseRegexp(left, start, not, false)
+	}
+	return left, nil
+}
+
+//newField 字段节点，路径可以匹配多个值时值为所有匹配的值组成的数组
+func newField(key string) *NodeField {
+	return &NodeField{
+		key:   key,
+		multi: isMultiPath(parsePath(key)),
+	}
+}
+
+func isNegatableKeyword(t *Token) bool {
+	for _, keyword := range []string{KeywordBetween, KeywordLike, KeywordILike, KeywordIn, KeywordRegexp, KeywordRlike} {
+		if isKeywordToken(t, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+//parseRegexp 解析正则表达式匹配，正则表达式必须是字符串，在解析时编译
+func (p *parser) parseRegexp(left Noder, leftStart *Token, not, ignoreCase bool) (Noder, error) {
+	value, err := p.asValue(left, leftStart)
+	if err != nil {
+		return nil, err
+	}
+	pattern := p.peek()
+	if pattern == nil || pattern.Type != TokenTypeString {
+		return nil, p.unexpected("a string pattern")
+	}
+	p.pos++
+	expr := pattern.Str
+	if ignoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, p.errorAt(pattern, "invalid regular expression: %v", err)
+	}
+	if not {
+		return &NodeNotRegexp{Value: value, Regexp: re}, nil
+	}
+	return &NodeRegexp{Value: value, Regexp: re}, nil
+}
+
+//parseIs 解析is [not] null、is [not] missing和is [not] true/false
+func (p *parser) parseIs(left Noder, leftStart *Token) (Noder, error) {
+	not := p.acceptKeyword(KeywordNot)
+	var n BoolNoder
+	switch {
+	case p.acceptKeyword(KeywordNULL):
+		value, err := p.asValue(left, leftStart)
+		if err != nil {
+			return nil, err
+		}
+		if not {
+			return &NodeIsNotNull{Value: value}, nil
+		}
+		return &NodeIsNull{Value: value}, nil
+	case p.acceptKeyword(KeywordMissing):
+		key, err := p.fieldKey(left, leftStart, KeywordIs)
+		if err != nil {
+			return nil, err
+		}
+		if not {
+			return &NodeIsNotMissing{Key: key}, nil
+		}
+		return &NodeIsMissing{Key: key}, nil
+	case p.acceptKeyword(KeywordTrue):
+		n = &NodeIsTrue{Node: left}
+	case p.acceptKeyword(KeywordFalse):
+		n = &NodeIsFalse{Node: left}
+	default:
+		return nil, p.unexpected("null, missing, true or false")
+	}
+	if not {
+		return &NodeNot{Node: n}, nil
+	}
+	return n, nil
+}
+
+//fieldKey is missing的左边只能是字段
+func (p *parser) fieldKey(n Noder, at *Token, keyword string) (string, error) {
+	field, ok := n.(*NodeField)
+	if !ok {
+		return "", p.errorAt(at, "left side of %s must be a field", keyword)
+	}
+	return field.key, nil
+}
+
+func (p *parser) parseComparison(left Noder, leftStart *Token) (Noder, error) {
+	op := p.next()
+	rightStart := p.peek()
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	leftI, err := p.asValue(left, leftStart)
+	if err != nil {
+		return nil, err
+	}
+	rightI, err := p.asValue(right, rightStart)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Str {
+	case OperatorEqual:
+		return &NodeEqual{
+			Left:  leftI,
+			Right: rightI,
+		}, nil
+	case OperatorNotEqual, OperatorNotEqual2:
+		return &NodeNotEqual{
+			Left:  leftI,
+			Right: rightI,
+		}, nil
+	case OperatorLessThan:
+		return &NodeLessThan{
+			Left:  leftI,
+			Right: rightI,
+		}, nil
+	case OperatorLessEqual:
+		return &NodeLessEqual{
+			Left:  leftI,
+			Right: rightI,
+		}, nil
+	case OperatorGreaterThan:
+		return &NodeGreaterThan{
+			Left:  leftI,
+			Right: rightI,
+		}, nil
+	default:
+		return &NodeGreaterEqual{
+			Left:  leftI,
+			Right: rightI,
+		}, nil
+	}
+}
+
+//parseBetween 解析[not] between low and high，low和high中不能直接出现and、or
+func (p *parser) parseBetween(left Noder, leftStart *Token, not bool) (Noder, error) {
+	p.pos++
+	value, err := p.asValue(left, leftStart)
+	if err != nil {
+		return nil, err
+	}
+	bounds := make([]InterfaceNoder, 2)
+	for i := range bounds {
+		if i == 1 {
+			if err := p.expectKeyword(KeywordAnd); err != nil {
+				return nil, err
+			}
+		}
+		start := p.peek()
+		n, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		bounds[i], err = p.asValue(n, start)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if not {
+		return &NodeNotBetween{Value: value, Low: bounds[0], High: bounds[1]}, nil
+	}
+	return &NodeBetween{Value: value, Low: bounds[0], High: bounds[1]}, nil
+}
+
+//parseMembership 解析[not] like、[not] ilike和[not] in
+func (p *parser) parseMembership(left Noder, leftStart *Token, not bool) (Noder, error) {
+	op := p.next()
+	keyword := strings.ToLower(op.Str)
+	if keyword == KeywordIn {
+		return p.parseIn(left, leftStart, not)
+	}
+	value, err := p.asValue(left, leftStart)
+	if err != nil {
+		return nil, err
+	}
+	pattern := p.peek()
+	if pattern == nil || pattern.Type != TokenTypeString {
+		return nil, p.unexpected("a string pattern")
+	}
+	p.pos++
+	var escape rune
+	if p.acceptKeyword(KeywordEscape) {
+		t := p.peek()
+		if t == nil || t.Type != TokenTypeString || utf8.RuneCountInString(t.Str) != 1 {
+			return nil, p.unexpected("a single character escape string")
+		}
+		p.pos++
+		escape, _ = utf8.DecodeRuneInString(t.Str)
+		if strings.HasSuffix(pattern.Str, t.Str) && !isEscaped(pattern.Str, escape) {
+			return nil, p.errorAt(pattern, "like pattern must not end with the escape character")
+		}
+	}
+	ignoreCase := keyword == KeywordILike
+	if not {
+		return &NodeNotLike{Value: value, Str: pattern.Str, Escape: escape, IgnoreCase: ignoreCase}, nil
+	}
+	return &NodeLike{Value: value, Str: pattern.Str, Escape: escape, IgnoreCase: ignoreCase}, nil
+}
+
+//isEscaped 判断pattern最后的转义字符本身是否被转义
+func isEscaped(pattern string, escape rune) bool {
+	count := 0
+	for len(pattern) > 0 {
+		r, n := utf8.DecodeLastRuneInString(pattern)
+		if r != escape {
+			break
+		}
+		count++
+		pattern = pattern[:len(pattern)-n]
+	}
+	return count%2 == 0
+}
+
+//parseIn 解析x in (a, b, ...)和x in tags，后者判断数组tags中是否包含x
+func (p *parser) parseIn(left Noder, leftStart *Token, not bool) (Noder, error) {
+	value, err := p.asValue(left, leftStart)
+	if err != nil {
+		return nil, err
+	}
+	n := &NodeIn{Value: value}
+	if t := p.peek(); t != nil && isLeftParen(t) {
+		p.pos++
+		n.List, err = p.parseValueList()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectRightParen(); err != nil {
+			return nil, err
+		}
+	} else {
+		start := p.peek()
+		array, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		n.Array, err = p.asValue(array, start)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if not {
+		return &NodeNotIn{NodeIn: *n}, nil
+	}
+	return n, nil
+}
+
+//parseAdditive 解析+、-、||，左结合
+func (p *parser) parseAdditive() (Noder, error) {
+	start := p.peek()
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator(OperatorPlus, OperatorMinus, OperatorConcat) {
+		op := p.next()
+		rightStart := p.peek()
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		if op.Str == OperatorConcat {
+			left, err = p.concat(left, start, right, rightStart)
+		} else {
+			left, err = p.arithmetic(op, left, start, right, rightStart)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+//parseMultiplicative 解析*、/、%、div，左结合
+func (p *parser) parseMultiplicative() (Noder, error) {
+	start := p.peek()
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator(OperatorMult, OperatorDiv, OperatorMod) || p.isKeyword(KeywordDiv) {
+		op := p.next()
+		rightStart := p.peek()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left, err = p.arithmetic(op, left, start, right, rightStart)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+//concat 字符串连接a || b，任意一边为null时结果为null
+func (p *parser) concat(left Noder, leftStart *Token, right Noder, rightStart *Token) (Noder, error) {
+	leftI, err := p.asValue(left, leftStart)
+	if err != nil {
+		return nil, err
+	}
+	rightI, err := p.asValue(right, rightStart)
+	if err != nil {
+		return nil, err
+	}
+	return &NodeFunction{
+		Name: OperatorConcat,
+		Args: []InterfaceNoder{leftI, rightI},
+		call: stringConcat,
+	}, nil
+}
+
+//parseUnary 解析一元的负号
+func (p *parser) parseUnary() (Noder, error) {
+	if !p.isOperator(OperatorMinus) {
+		return p.parsePrimary()
+	}
+	p.pos++
+	start := p.peek()
+	n, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	num, err := p.asNumber(n, start)
+	if err != nil {
+		return nil, err
+	}
+	return &NodeNeg{Node: num}, nil
+}
+
+func (p *parser) arithmetic(op *Token, left Noder, leftStart *Token, right Noder, rightStart *Token) (Noder, error) {
+	if op.Str == OperatorPlus || op.Str == OperatorMinus {
+		//加减法的操作数还可以是时间和时间间隔
+		leftI, err := p.asValue(left, leftStart)
+		if err != nil {
+			return nil, err
+		}
+		rightI, err := p.asValue(right, rightStart)
+		if err != nil {
+			return nil, err
+		}
+		if op.Str == OperatorPlus {
+			return &NodePlus{
+				Left:  leftI,
+				Right: rightI,
+			}, nil
+		}
+		return &NodeMinus{
+			Left:  leftI,
+			Right: rightI,
+		}, nil
+	}
+	leftN, err := p.asNumber(left, leftStart)
+	if err != nil {
+		return nil, err
+	}
+	rightN, err := p.asNumber(right, rightStart)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(op.Str) {
+	case KeywordDiv:
+		return &NodeIntDiv{
+			Left:  leftN,
+			Right: rightN,
+		}, nil
+	case OperatorMult:
+		return &NodeMult{
+			Left:  leftN,
+			Right: rightN,
+		}, nil
+	case OperatorDiv:
+		return &NodeDiv{
+			Left:  leftN,
+			Right: rightN,
+		}, nil
+	default:
+		return &NodeMod{
+			Left:  leftN,
+			Right: rightN,
+		}, nil
+	}
+}
+
+//parsePrimary 解析字面量、字段、函数调用和括号中的表达式
+func (p *parser) parsePrimary() (Noder, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, p.unexpected("an expression")
+	}
+	switch t.Type {
+	case TokenTypeLeftParen:
+		p.pos++
+		n, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectRightParen(); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case TokenTypeString:
+		p.pos++
+		return &NodeString{
+			str: t.Str,
+		}, nil
+	case TokenTypeNumber:
+		p.pos++
+		d, err := decimal.NewFromString(t.Str)
+		if err != nil {
+			return nil, p.errorAt(t, "invalid number %s", t)
+		}
+		return &NodeNumber{
+			d: d,
+		}, nil
+	case TokenTypeUnknow:
+		switch strings.ToLower(t.Str) {
+		case KeywordTrue:
+			p.pos++
+			return &NodeBool{b: true}, nil
+		case KeywordFalse:
+			p.pos++
+			return &NodeBool{b: false}, nil
+		case KeywordNULL:
+			p.pos++
+			return &NodeNull{}, nil
+		case KeywordCase:
+			if p.startsCase() {
+				return p.parseCase()
+			}
+		}
+		if isReserved(strings.ToLower(t.Str)) {
+			return nil, p.unexpected("an expression")
+		}
+		p.pos++
+		if next := p.peek(); strings.ToLower(t.Str) == KeywordInterval && next != nil && next.Type == TokenTypeString {
+			p.pos++
+			interval, err := parseInterval(next.Str)
+			if err != nil {
+				return nil, p.errorAt(next, "%v", err)
+			}
+			return &NodeInterval{interval: interval}, nil
+		}
+		if next := p.peek(); next != nil && isLeftParen(next) {
+			return p.parseFunction(t)
+		}
+		return newField(t.Str), nil
+	case TokenTypeIdentifier:
+		p.pos++
+		return newField(t.Str), nil
+	}
+	return nil, p.unexpected("an expression")
+}
+
+//parseFunction 解析函数调用，name为函数名，当前token为(
+func (p *parser) parseFunction(name *Token) (Noder, error) {
+	funcName := strings.ToLower(name.Str)
+	if isAggregateFunction(funcName) {
+		return p.parseAggregate(name, funcName)
+	}
+	switch funcName {
+	case "coalesce", "nullif", "if":
+		return p.parseConditional(name, funcName)
+	case "cast", "try_cast":
+		return p.parseCast(funcName == "try_cast")
+	case "exists":
+		return p.parseExists(name)
+	}
+	fn, ok := functions[funcName]
+	if !ok {
+		return nil, p.errorAt(name, "unknown function %s", name.Str)
+	}
+	p.pos++
+	args := make([]InterfaceNoder, 0)
+	if t := p.peek(); t == nil || !isRightParen(t) {
+		var err error
+		args, err = p.parseValueList()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expectRightParen(); err != nil {
+		return nil, err
+	}
+	if len(args) < fn.minArgs || fn.maxArgs >= 0 && len(args) > fn.maxArgs {
+		return nil, p.errorAt(name, "wrong number of arguments to %s: %d", funcName, len(args))
+	}
+	call := fn.call
+	if fn.bind != nil {
+		var err error
+		call, err = fn.bind(args)
+		if err != nil {
+			return nil, p.errorAt(name, "%s: %v", funcName, err)
+		}
+	}
+	return &NodeFunction{
+		Name:       funcName,
+		Args:       args,
+		call:       call,
+		zonedCall:  fn.zonedCall,
+		acceptNull: fn.acceptNull,
+	}, nil
+}
+
+//startsCase 当前的case是否为case表达式的开始，case后面不能跟着表达式时作为字段名
+func (p *parser) startsCase() bool {
+	if p.pos+1 >= len(p.tokens) {
+		return false
+	}
+	next := p.tokens[p.pos+1]
+	switch next.Type {
+	case TokenTypeKeyword, TokenTypeRightParen, TokenTypeOperator:
+		return false
+	case TokenTypeUnknow:
+		word := strings.ToLower(next.Str)
+		return !isReserved(word) || closingKeywords[word] || word == KeywordNot
+	}
+	return true
+}
+
+//parseCase 解析case表达式，当前token为case
+func (p *parser) parseCase() (Noder, error) {
+	p.pos++
+	n := &NodeCase{}
+	if !p.isKeyword(KeywordWhen) {
+		operand, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		n.Operand = operand
+	}
+	for p.acceptKeyword(KeywordWhen) {
+		var when CaseWhen
+		if n.Operand != nil {
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			when.Value = value
+		} else {
+			start := p.peek()
+			cond, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if when.Cond, err = p.asBool(cond, start); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.expectKeyword(KeywordThen); err != nil {
+			return nil, err
+		}
+		result, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		when.Result = result
+		n.Whens = append(n.Whens, when)
+	}
+	if len(n.Whens) == 0 {
+		return nil, p.unexpected(KeywordWhen)
+	}
+	if p.acceptKeyword(KeywordElse) {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		n.Else = value
+	}
+	if err := p.expectKeyword(KeywordEnd); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+//parseConditional 解析coalesce、nullif、if，参数只在需要时计算，当前token为(
+func (p *parser) parseConditional(name *Token, funcName string) (Noder, error) {
+	p.pos++
+	var cond BoolNoder
+	if funcName == "if" {
+		start := p.peek()
+		n, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if cond, err = p.asBool(n, start); err != nil {
+			return nil, err
+		}
+		if !p.isComma() {
+			return nil, p.unexpected(",")
+		}
+		p.pos++
+	}
+	args, err := p.parseValueList()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectRightParen(); err != nil {
+		return nil, err
+	}
+	switch funcName {
+	case "coalesce":
+		return &NodeCoalesce{Args: args}, nil
+	case "nullif":
+		if len(args) != 2 {
+			return nil, p.errorAt(name, "wrong number of arguments to %s: %d", funcName, len(args))
+		}
+		return &NodeNullIf{Value: args[0], Null: args[1]}, nil
+	}
+	if len(args) > 2 {
+		return nil, p.errorAt(name, "wrong number of arguments to %s: %d", funcName, len(args)+1)
+	}
+	n := &NodeIf{Cond: cond, Then: args[0]}
+	if len(args) > 1 {
+		n.Else = args[1]
+	}
+	return n, nil
+}
+
+//parseExists 解析exists(x)，x为字段或字段路径的字符串，当前token为(
+func (p *parser) parseExists(name *Token) (Noder, error) {
+	p.pos++
+	t := p.peek()
+	var key string
+	switch {
+	case t != nil && t.Type == TokenTypeString:
+		p.pos++
+		key = t.Str
+	default:
+		start := p.peek()
+		n, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		if key, err = p.fieldKey(n, start, name.Str); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expectRightParen(); err != nil {
+		return nil, err
+	}
+	return &NodeIsNotMissing{Key: key}, nil
+}
+
+//parseCast 解析cast(x as type)和try_cast(x as type)，当前token为(
+func (p *parser) parseCast(try bool) (Noder, error) {
+	p.pos++
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("as"); err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if t == nil || t.Type != TokenTypeUnknow {
+		return nil, p.unexpected("a type")
+	}
+	to, ok := castTypes[strings.ToLower(t.Str)]
+	if !ok {
+		return nil, p.errorAt(t, "unknown type %s", t.Str)
+	}
+	p.pos++
+	if err := p.expectRightParen(); err != nil {
+		return nil, err
+	}
+	return &NodeCast{Value: value, To: to, Try: try}, nil
+}
+
+//parseAggregate 解析聚合函数，支持count(*)和count(distinct x)
+func (p *parser) parseAggregate(name *Token, funcName string) (Noder, error) {
+	p.pos++
+	argStart := p.pos
+	n := &NodeAggregate{
+		Func: funcName,
+	}
+	if t := p.peek(); t != nil && t.Str == "*" && t.Type != TokenTypeString {
+		if funcName != AggregateCount {
+			return nil, p.errorAt(t, "%s(*) is not supported", funcName)
+		}
+		p.pos++
+	} else {
+		if t := p.peek(); isKeywordToken(t, "distinct") {
+			if funcName != AggregateCount {
+				return nil, p.errorAt(t, "%s(distinct) is not supported", funcName)
+			}
+			p.pos++
+			n.Distinct = true
+		}
+		aggCount := len(p.aggs)
+		arg, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		if len(p.aggs) > aggCount {
+			return nil, p.errorAt(p.aggs[aggCount].token, "aggregate function calls cannot be nested")
+		}
+		n.Arg = arg
+	}
+	argEnd := p.pos
+	if err := p.expectRightParen(); err != nil {
+		return nil, err
+	}
+	n.Key = funcName + "(" + tokensText(p.tokens[argStart:argEnd]) + ")"
+	p.aggs = append(p.aggs, aggregateRef{node: n, token: name})
+	return n, nil
+}
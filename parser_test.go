@@ -0,0 +1,198 @@
+package json_filter
+
+import (
+	"fmt"
+	"testing"
+)
+
+//evalValue 解析select中的表达式并计算它的值
+func evalValue(t *testing.T, expr string, getter Getter) interface{} {
+	t.Helper()
+	stmt, err := ParseStatement("select " + expr + " as x from t")
+	if err != nil {
+		t.Fatalf("%s: %v", expr, err)
+	}
+	v, err := stmt.Items[0].Expr.Interface(getter)
+	if err != nil {
+		t.Fatalf("%s: %v", expr, err)
+	}
+	return v
+}
+
+//evalBool 解析where中的条件并计算它的真假
+func evalBool(t *testing.T, cond string, getter Getter) bool {
+	t.Helper()
+	stmt, err := ParseStatement("select * from t where " + cond)
+	if err != nil {
+		t.Fatalf("%s: %v", cond, err)
+	}
+	b, err := stmt.Checker.Bool(getter)
+	if err != nil {
+		t.Fatalf("%s: %v", cond, err)
+	}
+	return b
+}
+
+func TestArithmeticPrecedence(t *testing.T) {
+	getter := mapGetter{"a": Number("1"), "b": Number("2"), "c": Number("3")}
+	cases := []struct {
+		expr string
+		want string
+	}{
+		{"a + b * c", "7"},
+		{"(a + b) * c", "9"},
+		{"c - a * b", "1"},
+		{"a - b - c", "-4"},
+		{"c * b / c", "2"},
+		{"c * b % c", "0"},
+		{"7 div b * c", "9"},
+		{"a + b || c", "33"},
+		//负号与减号
+		{"-a * b", "-2"},
+		{"-(a + b)", "-3"},
+		{"c -1", "2"},
+		{"c - -1", "4"},
+		{"c-a", "2"},
+		{"2 - -3", "5"},
+		{"-7 div 2", "-3"},
+		{"- -a", "1"},
+		{"b * -c", "-6"},
+		{"(-1)", "-1"},
+		{"abs(-c) -1", "2"},
+	}
+	for _, c := range cases {
+		if got := fmt.Sprint(evalValue(t, c.expr, getter)); got != c.want {
+			t.Errorf("%s = %s, want %s", c.expr, got, c.want)
+		}
+	}
+}
+
+func TestLogicPrecedence(t *testing.T) {
+	getter := mapGetter{"x": true, "y": false, "z": false, "a": Number("1")}
+	cases := []struct {
+		cond string
+		want bool
+	}{
+		{"x or y and z", true},
+		{"(x or y) and z", false},
+		{"y and z or x", true},
+		{"not y and z", false},
+		{"not (y and z)", true},
+		{"not a = 2", true},
+		{"a + 1 > a * 2", false},
+		{"a = 1 and y = false or z", true},
+		{"a between 0 and 2 and x", true},
+		{"a in (1, 2) and not y", true},
+		{"x and a -1 = 0", true},
+	}
+	for _, c := range cases {
+		if got := evalBool(t, c.cond, getter); got != c.want {
+			t.Errorf("%s = %v, want %v", c.cond, got, c.want)
+		}
+	}
+}
+
+func TestSyntaxErrorPosition(t *testing.T) {
+	cases := []struct {
+		sql     string
+		line    int
+		column  int
+		msg     string
+		snippet string
+	}{
+		{
+			"select level from t where level = 'error' and",
+			1, 46, "expected an expression, got end of sql",
+			"select level from t where level = 'error' and\n" +
+				"                                             ^",
+		},
+		{
+			"select a from t\nwhere (a = 1",
+			2, 13, "expected ), got end of sql",
+			"where (a = 1\n" +
+				"            ^",
+		},
+		{
+			"select a from t where a = 'x",
+			1, 27, "unterminated string",
+			"select a from t where a = 'x\n" +
+				"                          ^",
+		},
+		{
+			"select a,\n\tb + from t",
+			2, 6, "expected an expression, got from",
+			"\tb + from t\n" +
+				"\t    ^",
+		},
+		{
+			"select 数据 from t where 数据 = = 1",
+			1, 29, "expected an expression, got =",
+			"select 数据 from t where 数据 = = 1\n" +
+				"                            ^",
+		},
+	}
+	for _, c := range cases {
+		_, err := ParseStatement(c.sql)
+		se, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("%q: got %v, want a syntax error", c.sql, err)
+			continue
+		}
+		if se.Line != c.line || se.Column != c.column || se.Msg != c.msg {
+			t.Errorf("%q: got %d:%d %q, want %d:%d %q", c.sql, se.Line, se.Column, se.Msg, c.line, c.column, c.msg)
+		}
+		if snippet := se.Snippet(); snippet != c.snippet {
+			t.Errorf("%q: snippet\n%s\nwant\n%s", c.sql, snippet, c.snippet)
+		}
+	}
+}
+
+//TestContextualKeywords case、when、then、else、end只在case表达式中作为关键字
+func TestContextualKeywords(t *testing.T) {
+	getter := mapGetter{"case": Number("1"), "when": Number("2"), "then": Number("3"), "else": Number("4"), "end": Number("5")}
+	cases := []struct {
+		expr string
+		want string
+	}{
+		{"case", "1"},
+		{"end - 1", "4"},
+		{"case + when * then", "7"},
+		{"case when end = 5 then else else then end", "4"},
+		{"case (case) when 1 then then end", "3"},
+		{"case when when > 2 then 1 else -1 end", "-1"},
+	}
+	for _, c := range cases {
+		if got := fmt.Sprint(evalValue(t, c.expr, getter)); got != c.want {
+			t.Errorf("%s = %s, want %s", c.expr, got, c.want)
+		}
+	}
+}
+
+//TestItemName 没有别名时用表达式的sql文本作为输出的字段名
+func TestItemName(t *testing.T) {
+	cases := []struct {
+		sql  string
+		name string
+	}{
+		{"a", "a"},
+		{"a+1", "a + 1"},
+		{"concat(a, ',')", "concat(a, ',')"},
+		{"concat(a,',',b)", "concat(a, ',', b)"},
+		{"upper( a )", "upper(a)"},
+		{"a div (b)", "a div (b)"},
+		{"count(*)", "count(*)"},
+		{"`http.status`", "http.status"},
+		{"a + 1 as b", "b"},
+		{"a as 'x y'", "x y"},
+	}
+	for _, c := range cases {
+		stmt, err := ParseStatement("select " + c.sql + " from t")
+		if err != nil {
+			t.Errorf("%s: %v", c.sql, err)
+			continue
+		}
+		if name := stmt.Items[0].Name; name != c.name {
+			t.Errorf("%s: name %q, want %q", c.sql, name, c.name)
+		}
+	}
+}
@@ -0,0 +1,461 @@
+package json_filter
+
+import (
+	"strconv"
+	"strings"
+
+	json "github.com/json-iterator/go"
+)
+
+//quotePathSegment 字段名中含有.、双引号、方括号或空白，或者为*时用双引号括起来，作为路径中的一级
+func quotePathSegment(s string) string {
+	if s != "" && s != "*" && !strings.ContainsAny(s, ".\"[] \t\r\n") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+type segmentKind int8
+
+const (
+	//segmentKey 对象的字段
+	segmentKey segmentKind = iota
+	//segmentIndex 数组的下标[n]，负数从末尾开始计算
+	segmentIndex
+	//segmentSlice 数组的切片[a:b]，a、b都可以省略
+	segmentSlice
+	//segmentWildcard *，对象的所有字段或数组的所有元素
+	segmentWildcard
+	//segmentDescent ..，当前的值以及它下面任意深度的值，如..error
+	segmentDescent
+)
+
+//pathSegment 字段路径中的一级
+type pathSegment struct {
+	kind  segmentKind
+	key   string
+	index int
+	//start、end 切片的起止下标，hasStart、hasEnd为false时表示省略
+	start, end       int
+	hasStart, hasEnd bool
+}
+
+func (s pathSegment) String() string {
+	switch s.kind {
+	case segmentIndex:
+		return "[" + strconv.Itoa(s.index) + "]"
+	case segmentSlice:
+		var b strings.Builder
+		b.WriteByte('[')
+		if s.hasStart {
+			b.WriteString(strconv.Itoa(s.start))
+		}
+		b.WriteByte(':')
+		if s.hasEnd {
+			b.WriteString(strconv.Itoa(s.end))
+		}
+		b.WriteByte(']')
+		return b.String()
+	case segmentWildcard:
+		return "*"
+	case segmentDescent:
+		return ""
+	}
+	return s.key
+}
+
+//parsePath 解析字段的路径，如data.items[0].sku、data.items[-1]、data.items[1:3]、data."http.status"、
+//data.*.id、..error，方括号中也可以是带引号的字段名，如data["http.status"]，不能解析的方括号作为字段名的一部分
+func parsePath(key string) []pathSegment {
+	if isSimplePath(key) {
+		keys := strings.Split(key, ".")
+		segments := make([]pathSegment, len(keys))
+		for i, k := range keys {
+			segments[i] = pathSegment{key: k}
+		}
+		return segments
+	}
+	segments := make([]pathSegment, 0)
+	var b strings.Builder
+	//quoted 当前这一级是否有带引号的部分，带引号的*是普通的字段名
+	quoted := false
+	//open 是否在开头或.后面，这时字段名为空也作为一级
+	open := true
+	push := func() {
+		seg := pathSegment{key: b.String()}
+		if !quoted && seg.key == "*" {
+			seg = pathSegment{kind: segmentWildcard}
+		}
+		segments = append(segments, seg)
+		b.Reset()
+		quoted = false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch c {
+		case '"':
+			s, n := unquotePathSegment(key[i:], '"')
+			b.WriteString(s)
+			i += n - 1
+			quoted = true
+		case '.':
+			if i+1 < len(key) && key[i+1] == '.' {
+				if b.Len() > 0 || quoted {
+					push()
+				}
+				segments = append(segments, pathSegment{kind: segmentDescent})
+				i++
+			} else if open || b.Len() > 0 || quoted {
+				push()
+			}
+			open = true
+		case '[':
+			seg, n, ok := parseBracket(key[i:])
+			if !ok {
+				b.WriteByte(c)
+				break
+			}
+			if b.Len() > 0 || quoted {
+				push()
+			}
+			segments = append(segments, seg)
+			open = false
+			i += n - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	if open || b.Len() > 0 || quoted {
+		push()
+	}
+	return segments
+}
+
+//isSimplePath 路径中是否只有用.分隔的字段名
+func isSimplePath(key string) bool {
+	return !strings.ContainsAny(key, `"[*`) && !strings.Contains(key, "..")
+}
+
+//unquotePathSegment 解析s开头用quote括起来的部分，两个连续的引号表示引号本身，
+//返回去掉引号后的内容和占用的字节数，没有结束的引号时到字符串末尾为止
+func unquotePathSegment(s string, quote byte) (string, int) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		if s[i] != quote {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == quote {
+			b.WriteByte(quote)
+			i++
+			continue
+		}
+		return b.String(), i + 1
+	}
+	return b.String(), len(s)
+}
+
+//parseBracket 解析s开头的[n]、[a:b]、["key"]或['key']，返回这一级和占用的字节数
+func parseBracket(s string) (pathSegment, int, bool) {
+	if len(s) > 1 && (s[1] == '"' || s[1] == '\'') {
+		k, n := unquotePathSegment(s[1:], s[1])
+		if 1+n < len(s) && s[1+n] == ']' {
+			return pathSegment{key: k}, n + 2, true
+		}
+		return pathSegment{}, 0, false
+	}
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return pathSegment{}, 0, false
+	}
+	inner := strings.TrimSpace(s[1:end])
+	colon := strings.IndexByte(inner, ':')
+	if colon < 0 {
+		index, err := strconv.Atoi(inner)
+		if err != nil {
+			return pathSegment{}, 0, false
+		}
+		return pathSegment{kind: segmentIndex, index: index}, end + 1, true
+	}
+	seg := pathSegment{kind: segmentSlice}
+	var err error
+	if low := strings.TrimSpace(inner[:colon]); low != "" {
+		if seg.start, err = strconv.Atoi(low); err != nil {
+			return pathSegment{}, 0, false
+		}
+		seg.hasStart = true
+	}
+	if high := strings.TrimSpace(inner[colon+1:]); high != "" {
+		if seg.end, err = strconv.Atoi(high); err != nil {
+			return pathSegment{}, 0, false
+		}
+		seg.hasEnd = true
+	}
+	return seg, end + 1, true
+}
+
+//splitPath 将字段的路径拆分为多级的名字，双引号括起来的部分作为一级，如data."http.status"，
+//数组的下标和切片跟在前一级的名字后面，如data.items[0]拆分为data和items[0]，
+//..加在后一级的名字前面，如data..id拆分为data和..id
+func splitPath(key string) []string {
+	segments := parsePath(key)
+	names := make([]string, 0, len(segments))
+	prefix := ""
+	for _, seg := range segments {
+		switch {
+		case seg.kind == segmentDescent:
+			prefix = ".."
+		case (seg.kind == segmentIndex || seg.kind == segmentSlice) && prefix == "" && len(names) > 0:
+			names[len(names)-1] += seg.String()
+		default:
+			names = append(names, prefix+seg.String())
+			prefix = ""
+		}
+	}
+	if prefix != "" {
+		names = append(names, prefix)
+	}
+	return names
+}
+
+//sliceBounds 按数组的长度计算切片的起止下标，负数从末尾开始计算，超出范围的部分忽略
+func (s pathSegment) sliceBounds(size int) (int, int) {
+	start, end := 0, size
+	if s.hasStart {
+		start = clampIndex(s.start, size)
+	}
+	if s.hasEnd {
+		end = clampIndex(s.end, size)
+	}
+	if start > end {
+		start = end
+	}
+	return start, end
+}
+
+func clampIndex(i, size int) int {
+	if i < 0 {
+		i += size
+	}
+	if i < 0 {
+		return 0
+	}
+	if i > size {
+		return size
+	}
+	return i
+}
+
+//walkPath 按路径从json中取值，ok为false时字段不存在，
+//路径可以匹配多个值时结果为所有匹配的值组成的数组，ok为false时没有匹配的值
+func walkPath(data []byte, segments []pathSegment) (interface{}, bool) {
+	if isMultiPath(segments) {
+		iter := exactJSON.BorrowIterator(data)
+		defer exactJSON.ReturnIterator(iter)
+		root := readJSONNode(iter)
+		if iter.Error != nil {
+			return []interface{}{}, false
+		}
+		values := make([]interface{}, 0)
+		matchPath(root, segments, func(n *jsonNode) {
+			values = append(values, n.Interface())
+		})
+		return values, len(values) > 0
+	}
+	j := exactJSON.Get(data)
+	for _, seg := range segments {
+		if seg.kind == segmentKey {
+			j = j.Get(seg.key)
+		} else {
+			if j.ValueType() != json.ArrayValue {
+				return nil, false
+			}
+			index := seg.index
+			if index < 0 {
+				index += j.Size()
+			}
+			if index < 0 {
+				return nil, false
+			}
+			j = j.Get(index)
+		}
+		if j.ValueType() == json.InvalidValue {
+			return nil, false
+		}
+	}
+	return j.GetInterface(), true
+}
+
+//jsonNode 解析后的json，对象的字段保持在json中的顺序，
+//用于匹配多个值的路径，只解析一遍json，遍历所有的字段时不用重复解析
+type jsonNode struct {
+	typ json.ValueType
+	//keys 对象的字段名，与children一一对应
+	keys []string
+	//children 对象的字段的值或数组的元素
+	children []*jsonNode
+	//value 字符串、数值、布尔值或null
+	value interface{}
+}
+
+//readJSONNode 从iter中读取一个json值
+func readJSONNode(iter *json.Iterator) *jsonNode {
+	n := &jsonNode{typ: iter.WhatIsNext()}
+	switch n.typ {
+	case json.ObjectValue:
+		iter.ReadObjectCB(func(iter *json.Iterator, key string) bool {
+			n.keys = append(n.keys, key)
+			n.children = append(n.children, readJSONNode(iter))
+			return true
+		})
+	case json.ArrayValue:
+		iter.ReadArrayCB(func(iter *json.Iterator) bool {
+			n.children = append(n.children, readJSONNode(iter))
+			return true
+		})
+	default:
+		n.value = iter.Read()
+	}
+	return n
+}
+
+//get 返回对象中名为key的字段的值，不存在时返回nil
+func (n *jsonNode) get(key string) *jsonNode {
+	if n.typ != json.ObjectValue {
+		return nil
+	}
+	for i, k := range n.keys {
+		if k == key {
+			return n.children[i]
+		}
+	}
+	return nil
+}
+
+//Interface 转换为map、[]interface{}等值
+func (n *jsonNode) Interface() interface{} {
+	switch n.typ {
+	case json.ObjectValue:
+		m := make(map[string]interface{}, len(n.keys))
+		for i, k := range n.keys {
+			m[k] = n.children[i].Interface()
+		}
+		return m
+	case json.ArrayValue:
+		a := make([]interface{}, len(n.children))
+		for i, child := range n.children {
+			a[i] = child.Interface()
+		}
+		return a
+	}
+	return n.value
+}
+
+//matchPath 按路径查找所有匹配的值，按在json中出现的顺序对每个值调用fn
+func matchPath(n *jsonNode, segments []pathSegment, fn func(*jsonNode)) {
+	if n == nil {
+		return
+	}
+	if len(segments) == 0 {
+		fn(n)
+		return
+	}
+	seg, rest := segments[0], segments[1:]
+	switch seg.kind {
+	case segmentKey:
+		matchPath(n.get(seg.key), rest, fn)
+	case segmentIndex:
+		if n.typ != json.ArrayValue {
+			return
+		}
+		index := seg.index
+		if index < 0 {
+			index += len(n.children)
+		}
+		if index >= 0 && index < len(n.children) {
+			matchPath(n.children[index], rest, fn)
+		}
+	case segmentSlice:
+		if n.typ != json.ArrayValue {
+			return
+		}
+		start, end := seg.sliceBounds(len(n.children))
+		for _, child := range n.children[start:end] {
+			matchPath(child, rest, fn)
+		}
+	case segmentWildcard:
+		for _, child := range n.children {
+			matchPath(child, rest, fn)
+		}
+	case segmentDescent:
+		matchPath(n, rest, fn)
+		for _, child := range n.children {
+			matchPath(child, segments, fn)
+		}
+	}
+}
+
+//isMultiPath 路径中有切片、*或..时可以匹配多个值
+func isMultiPath(segments []pathSegment) bool {
+	for _, seg := range segments {
+		switch seg.kind {
+		case segmentSlice, segmentWildcard, segmentDescent:
+			return true
+		}
+	}
+	return false
+}
+
+//isPlainPath 路径中是否只有对象的字段和非负的下标，这时可以直接使用json-iterator的Get
+func isPlainPath(segments []pathSegment) bool {
+	for _, seg := range segments {
+		if seg.kind != segmentKey && (seg.kind != segmentIndex || seg.index < 0) {
+			return false
+		}
+	}
+	return true
+}
+
+//pathKeys 将只有字段和非负下标的路径转换为json-iterator Get的参数
+func pathKeys(segments []pathSegment) []interface{} {
+	keys := make([]interface{}, len(segments))
+	for i, seg := range segments {
+		if seg.kind == segmentIndex {
+			keys[i] = seg.index
+		} else {
+			keys[i] = seg.key
+		}
+	}
+	return keys
+}
+
+//formatPath 将路径还原为sql中的写法，quote为true时字段名按需加上双引号
+func formatPath(segments []pathSegment, quote bool) string {
+	var b strings.Builder
+	for i, seg := range segments {
+		switch seg.kind {
+		case segmentIndex, segmentSlice:
+			b.WriteString(seg.String())
+			continue
+		case segmentDescent:
+			//..后面是下标或切片时没有.分隔，需要写成..[0]
+			b.WriteByte('.')
+			if i+1 == len(segments) || segments[i+1].kind == segmentIndex || segments[i+1].kind == segmentSlice {
+				b.WriteByte('.')
+			}
+			continue
+		}
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		switch {
+		case seg.kind == segmentWildcard:
+			b.WriteByte('*')
+		case quote:
+			b.WriteString(quoteIdentifier(seg.key))
+		default:
+			b.WriteString(seg.key)
+		}
+	}
+	return b.String()
+}
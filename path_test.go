@@ -0,0 +1,214 @@
+package json_filter
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGetDataFromJSON(t *testing.T) {
+	data := []byte(`{"id":1,"data":{"items":[{"sku":"a1","qty":2},{"sku":"a2","qty":5},{"sku":"a3"}],` +
+		`"http.status":200,"*":"star","a[0]":"lit","x":{"error":"e1","y":[{"error":"e2"}]}},"error":"e0"}`)
+	cases := []struct {
+		key  string
+		want string
+	}{
+		{"id", "1"},
+		{"data.items[0].sku", "a1"},
+		{"data.items[-1].sku", "a3"},
+		{"data.items[3]", "<nil>"},
+		{"data.items[-4]", "<nil>"},
+		{"data.items[1:].sku", "[a2 a3]"},
+		{"data.items[:-1].qty", "[2 5]"},
+		{"data.items[5:]", "[]"},
+		{"data.items[:].qty", "[2 5]"},
+		{`data."http.status"`, "200"},
+		{`data["http.status"]`, "200"},
+		{`data['http.status']`, "200"},
+		{`data."a[0]"`, "lit"},
+		{`data."*"`, "star"},
+		{"data.items[x]", "<nil>"},
+		{"data.items.*.sku", "[a1 a2 a3]"},
+		{"data.x.*", "[e1 [map[error:e2]]]"},
+		{"..error", "[e0 e1 e2]"},
+		{"data..error", "[e1 e2]"},
+		{"..y[0].error", "[e2]"},
+		{"..missing", "[]"},
+	}
+	for _, c := range cases {
+		v, err := GetDataFromJSON(data, c.key)
+		if err != nil {
+			t.Errorf("%s: %v", c.key, err)
+			continue
+		}
+		if got := fmt.Sprint(v); got != c.want {
+			t.Errorf("%s = %s, want %s", c.key, got, c.want)
+		}
+	}
+}
+
+func TestExistsInJSON(t *testing.T) {
+	data := []byte(`{"a":null,"b":{"c":[1,{"d":null}]}}`)
+	cases := []struct {
+		key  string
+		want bool
+	}{
+		{"a", true},
+		{"x", false},
+		{"b.c[0]", true},
+		{"b.c[1].d", true},
+		{"b.c[2]", false},
+		{"b.c[-2]", true},
+		{"..d", true},
+		{"..e", false},
+		{"b.*.x", false},
+		{"b.c[5:]", false},
+	}
+	for _, c := range cases {
+		if got := existsInJSON(data, c.key); got != c.want {
+			t.Errorf("exists(%s) = %v, want %v", c.key, got, c.want)
+		}
+	}
+}
+
+func TestFieldPathTokens(t *testing.T) {
+	cases := []struct {
+		sql   string
+		key   string
+		name  string
+		multi bool
+	}{
+		{"data.items[0].sku", "data.items[0].sku", "data.items[0].sku", false},
+		{"data.items[ -1 ]", "data.items[-1]", "data.items[-1]", false},
+		{`data["http.status"]`, `data."http.status"`, "data.http.status", false},
+		{"`data`.`a b`", `data."a b"`, "data.a b", false},
+		{"data.items[1:]", "data.items[1:]", "data.items[1:]", true},
+		{"data.*.id", "data.*.id", "data.*.id", true},
+		{`data."*".id`, `data."*".id`, "data.*.id", false},
+		{"..error", "..error", "..error", true},
+		{"data..items[0]", "data..items[0]", "data..items[0]", true},
+		{"[keys]", "[keys]", "[keys]", false},
+	}
+	for _, c := range cases {
+		stmt, err := ParseStatement("select " + c.sql + " from t")
+		if err != nil {
+			t.Errorf("%s: %v", c.sql, err)
+			continue
+		}
+		field, ok := stmt.Items[0].Expr.(*NodeField)
+		if !ok {
+			t.Errorf("%s: got %T, want a field", c.sql, stmt.Items[0].Expr)
+			continue
+		}
+		if field.key != c.key || stmt.Items[0].Name != c.name || field.multi != c.multi {
+			t.Errorf("%s: got key %s, name %s, multi %v, want %s, %s, %v",
+				c.sql, field.key, stmt.Items[0].Name, field.multi, c.key, c.name, c.multi)
+		}
+	}
+}
+
+func TestSplitPath(t *testing.T) {
+	cases := []struct {
+		key  string
+		want []string
+	}{
+		{"a.b", []string{"a", "b"}},
+		{`a."b.c"`, []string{"a", "b.c"}},
+		{"a.items[0].b", []string{"a", "items[0]", "b"}},
+		{"a..b", []string{"a", "..b"}},
+		{"..b[1:2]", []string{"..b[1:2]"}},
+		{"a.*", []string{"a", "*"}},
+	}
+	for _, c := range cases {
+		if got := splitPath(c.key); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("splitPath(%s) = %q, want %q", c.key, got, c.want)
+		}
+	}
+}
+
+//TestAnyMatch 条件中用到可以匹配多个值的字段时有一个值满足条件即成立
+func TestAnyMatch(t *testing.T) {
+	getter := mapGetter{
+		"data.*.id":   []interface{}{Number("5"), Number("7"), nil},
+		"data.*.name": []interface{}{"xa", "b"},
+		"..none":      []interface{}{},
+	}
+	cases := []struct {
+		cond string
+		want bool
+	}{
+		{"data.*.id = 5", true},
+		{"data.*.id = 6", false},
+		{"not data.*.id = 5", false},
+		{"data.*.id > 6 and data.*.id < 6", true},
+		{"data.*.id between 6 and 8", true},
+		{"data.*.id is null", true},
+		{"data.*.id in (1, 7)", true},
+		{"starts_with(data.*.name, 'x')", true},
+		{"not starts_with(data.*.name, 'y')", true},
+		{"..none is null", false},
+		{"not ..none = 1", true},
+	}
+	for _, c := range cases {
+		if got := evalBool(t, c.cond, getter); got != c.want {
+			t.Errorf("%s = %v, want %v", c.cond, got, c.want)
+		}
+	}
+}
+
+//TestInvalidBracket 字段路径中不能解析的方括号报语法错误
+func TestInvalidBracket(t *testing.T) {
+	cases := []struct {
+		sql    string
+		column int
+		msg    string
+	}{
+		{"select items[x from t", 13, "invalid array index or slice [x"},
+		{"select items[1:a] from t", 13, "invalid array index or slice [1:a]"},
+		{"select a from t where data.items[] = 1", 33, "invalid array index or slice []"},
+		{"select a[keys] from t", 9, "invalid array index or slice [keys]"},
+		{`select data["x] from t`, 12, "invalid array index or slice [\"x]"},
+	}
+	for _, c := range cases {
+		_, err := ParseStatement(c.sql)
+		se, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("%q: got %v, want a syntax error", c.sql, err)
+			continue
+		}
+		if se.Line != 1 || se.Column != c.column || se.Msg != c.msg {
+			t.Errorf("%q: got %d:%d %q, want 1:%d %q", c.sql, se.Line, se.Column, se.Msg, c.column, c.msg)
+		}
+	}
+}
+
+//TestWalkPathWide 字段很多时*和..按顺序返回所有匹配的值
+func TestWalkPathWide(t *testing.T) {
+	const n = 5000
+	var b strings.Builder
+	b.WriteString(`{"items":[`)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `{"k%d":{"x":%d}}`, i, i)
+	}
+	b.WriteString(`]}`)
+	for _, key := range []string{"..x", "items.*.*.x"} {
+		v, err := GetDataFromJSON([]byte(b.String()), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values := v.([]interface{})
+		if len(values) != n {
+			t.Fatalf("%s: %d values, want %d", key, len(values), n)
+		}
+		for i, x := range values {
+			if x != Number(strconv.Itoa(i)) {
+				t.Fatalf("%s: values[%d] = %v", key, i, x)
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package json_filter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+//RunStatements 执行cfg.SQL中用;分隔的多条语句，输入只读取一次，
+//第i条语句的结果写到outputs[i]，每行一个json
+func RunStatements(cfg FilterConfig, outputs []io.Writer) error {
+	stmts, err := ParseStatements(cfg.SQL)
+	if err != nil {
+		return err
+	}
+	if len(stmts) != len(outputs) {
+		return fmt.Errorf("%d statements but %d outputs", len(stmts), len(outputs))
+	}
+	//各条语句在不同的goroutine中执行，共用同一个加锁的错误输出
+	if cfg.ErrWriter != nil {
+		cfg.ErrWriter = &syncWriter{w: cfg.ErrWriter}
+	}
+	filters := make([]*JSONFilter, len(stmts))
+	readers := make([]*io.PipeReader, len(stmts))
+	writers := make([]*io.PipeWriter, len(stmts))
+	for i, stmt := range stmts {
+		readers[i], writers[i] = io.Pipe()
+		stmtCfg := cfg
+		stmtCfg.Reader = readers[i]
+		filters[i], err = newJSONFilter(stmt, stmtCfg)
+		if err != nil {
+			return fmt.Errorf("statement %d: %w", i+1, err)
+		}
+	}
+	errs := make([]error, len(stmts))
+	var wg sync.WaitGroup
+	for i := range filters {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = runFilter(filters[i], outputs[i])
+			//语句提前结束(如达到limit)时关闭管道，不再向它写入数据
+			readers[i].Close()
+		}(i)
+	}
+	readErr := broadcast(cfg.Reader, writers)
+	wg.Wait()
+	if readErr != nil {
+		return readErr
+	}
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("statement %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+//runFilter 输出一条语句的所有结果，处理数据时的错误已写入errWriter，只返回输出时的错误
+func runFilter(f *JSONFilter, w io.Writer) error {
+	defer f.Close()
+	for f.Next() {
+		line, err := f.GetData()
+		if err != nil {
+			fmt.Fprintln(f.errWriter, err)
+			continue
+		}
+		if _, err := fmt.Fprintln(w, string(line)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//broadcast 将r中的数据依次写入每个管道，所有管道都关闭后不再读取
+func broadcast(r io.Reader, writers []*io.PipeWriter) error {
+	buf := make([]byte, 32*1024)
+	active := len(writers)
+	for active > 0 {
+		n, err := r.Read(buf)
+		if n > 0 {
+			for i, w := range writers {
+				if w == nil {
+					continue
+				}
+				if _, err := w.Write(buf[:n]); err != nil {
+					writers[i] = nil
+					active--
+				}
+			}
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = nil
+			}
+			for _, w := range writers {
+				if w != nil {
+					w.CloseWithError(err)
+				}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+//syncWriter 加锁的Writer，可以在多个goroutine中同时写入
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
@@ -0,0 +1,77 @@
+package json_filter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRunStatementsErrWriter(t *testing.T) {
+	var input strings.Builder
+	for i := 0; i < 1000; i++ {
+		input.WriteString(`{"a":1,"b":0}` + "\n")
+	}
+	var errBuf bytes.Buffer
+	outputs := []io.Writer{&bytes.Buffer{}, &bytes.Buffer{}}
+	cfg := FilterConfig{
+		Reader:    strings.NewReader(input.String()),
+		ErrWriter: &errBuf,
+		SQL:       "select a / b as x from t; select a % b as y from t",
+	}
+	if err := RunStatements(cfg, outputs); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(errBuf.String()), "\n")
+	if len(lines) != 2000 {
+		t.Fatalf("got %d error lines, want 2000", len(lines))
+	}
+	for _, line := range lines {
+		if line != errDivisionByZero.Error() {
+			t.Fatalf("unexpected error line %q", line)
+		}
+	}
+	for i, w := range outputs {
+		if w.(*bytes.Buffer).Len() != 0 {
+			t.Errorf("statement %d output %q, want empty", i+1, w.(*bytes.Buffer).String())
+		}
+	}
+}
+
+//TestRunStatements 多条语句共用一次输入，结果分别写到各自的输出
+func TestRunStatements(t *testing.T) {
+	var input strings.Builder
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&input, `{"i":%d,"level":"%s"}`+"\n", i, []string{"info", "error"}[i%2])
+	}
+	sql := `-- 前两条错误
+select i from t where level = 'error' limit 2;
+/* 按level计数 */
+select level, count(*) as c from t group by level;
+select max(i) as m from t -- 最大值
+`
+	outputs := []io.Writer{&bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{}}
+	var errBuf bytes.Buffer
+	cfg := FilterConfig{Reader: strings.NewReader(input.String()), ErrWriter: &errBuf, SQL: sql}
+	if err := RunStatements(cfg, outputs); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"{\"i\":1}\n{\"i\":3}\n",
+		"{\"level\":\"info\",\"c\":50}\n{\"level\":\"error\",\"c\":50}\n",
+		"{\"m\":99}\n",
+	}
+	for i, w := range outputs {
+		if got := w.(*bytes.Buffer).String(); got != want[i] {
+			t.Errorf("statement %d: output %q, want %q", i+1, got, want[i])
+		}
+	}
+	if errBuf.Len() != 0 {
+		t.Errorf("errors %q", errBuf.String())
+	}
+	cfg.Reader = strings.NewReader(input.String())
+	if err := RunStatements(cfg, outputs[:2]); err == nil || err.Error() != "3 statements but 2 outputs" {
+		t.Errorf("got error %v", err)
+	}
+}
@@ -0,0 +1,423 @@
+package json_filter
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/gob"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/shopspring/decimal"
+)
+
+//defaultSortMemoryLimit 默认排序内存上限
+const defaultSortMemoryLimit = 64 << 20
+
+func init() {
+	gob.Register(Number(""))
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+	gob.Register(time.Time{})
+	gob.Register(Interval(0))
+}
+
+//OrderByItem order by中的一项
+type OrderByItem struct {
+	Expr       InterfaceNoder
+	Desc       bool
+	NullsFirst bool
+}
+
+//sortKeys 计算一行数据的排序键
+func sortKeys(items []OrderByItem, getter Getter) ([]interface{}, error) {
+	keys := make([]interface{}, len(items))
+	for i, item := range items {
+		v, err := item.Expr.Interface(getter)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = v
+	}
+	return keys, nil
+}
+
+//compareKeys 按order by的规则比较两组排序键
+func compareKeys(items []OrderByItem, a, b []interface{}) int {
+	for i, item := range items {
+		x, y := a[i], b[i]
+		if x == nil || y == nil {
+			if x == nil && y == nil {
+				continue
+			}
+			if (x == nil) == item.NullsFirst {
+				return -1
+			}
+			return 1
+		}
+		c := compareValues(x, y)
+		if item.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+//valueRank 不同类型之间的排序顺序: null < bool < number < string < 时间 < 时间间隔 < 其他
+func valueRank(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case Number, decimal.Decimal, float64:
+		return 2
+	case string:
+		return 3
+	case time.Time:
+		return 4
+	case Interval:
+		return 5
+	default:
+		return 6
+	}
+}
+
+//compareValues 比较两个值，数字按数值比较，字符串按字典序比较
+func compareValues(a, b interface{}) int {
+	ra, rb := valueRank(a), valueRank(b)
+	if ra != rb {
+		if ra < rb {
+			return -1
+		}
+		return 1
+	}
+	if ra == 2 {
+		x, errX := toNumber(a)
+		y, errY := toNumber(b)
+		if errX == nil && errY == nil {
+			return x.Cmp(y)
+		}
+	}
+	switch x := a.(type) {
+	case nil:
+		return 0
+	case bool:
+		y := b.(bool)
+		if x == y {
+			return 0
+		}
+		if !x {
+			return -1
+		}
+		return 1
+	case string:
+		return strings.Compare(x, b.(string))
+	case time.Time:
+		return compareTime(x, b.(time.Time))
+	case Interval:
+		y := b.(Interval)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	default:
+		bsA, _ := json.Marshal(a)
+		bsB, _ := json.Marshal(b)
+		return strings.Compare(string(bsA), string(bsB))
+	}
+}
+
+//sortRecord 待排序的一行数据
+type sortRecord struct {
+	Keys []interface{}
+	Data []byte
+	seq  int
+}
+
+//size 估算数据占用的内存
+func (r sortRecord) size() int {
+	n := len(r.Data) + 48
+	for _, k := range r.Keys {
+		n += 16
+		if s, ok := k.(string); ok {
+			n += len(s)
+		}
+	}
+	return n
+}
+
+type sortConfig struct {
+	memoryLimit int
+	tempDir     string
+	//topN 大于0时只保留排序后的前topN行，这些行超出内存上限时也写入临时文件，
+	//每个临时文件只保留前topN行
+	topN int
+}
+
+//externalSorter 外部排序，内存中的数据超过上限时排好序写入临时文件，最后多路归并
+type externalSorter struct {
+	items   []OrderByItem
+	cfg     sortConfig
+	records []sortRecord
+	memUsed int
+	files   []*os.File
+	runs    *runHeap
+	index   int
+	seq     int
+}
+
+func newExternalSorter(items []OrderByItem, cfg sortConfig) *externalSorter {
+	if cfg.memoryLimit <= 0 {
+		cfg.memoryLimit = defaultSortMemoryLimit
+	}
+	return &externalSorter{
+		items: items,
+		cfg:   cfg,
+	}
+}
+
+func (s *externalSorter) sortRecords() {
+	sort.SliceStable(s.records, func(i, j int) bool {
+		return s.less(s.records[i], s.records[j])
+	})
+}
+
+//less 排序键相同时先添加的数据排在前面
+func (s *externalSorter) less(a, b sortRecord) bool {
+	c := compareKeys(s.items, a.Keys, b.Keys)
+	if c != 0 {
+		return c < 0
+	}
+	return a.seq < b.seq
+}
+
+//Add 添加一行数据
+func (s *externalSorter) Add(rec sortRecord) error {
+	rec.seq = s.seq
+	s.seq++
+	if s.cfg.topN > 0 && len(s.files) == 0 {
+		s.addTopN(rec)
+		if s.memUsed >= s.cfg.memoryLimit {
+			return s.spill()
+		}
+		return nil
+	}
+	s.records = append(s.records, rec)
+	s.memUsed += rec.size()
+	if s.memUsed >= s.cfg.memoryLimit {
+		return s.spill()
+	}
+	return nil
+}
+
+//addTopN 用大顶堆保留最小的topN行
+func (s *externalSorter) addTopN(rec sortRecord) {
+	h := topNHeap{s}
+	if len(s.records) < s.cfg.topN {
+		heap.Push(h, rec)
+		s.memUsed += rec.size()
+		return
+	}
+	if s.less(rec, s.records[0]) {
+		s.memUsed += rec.size() - s.records[0].size()
+		s.records[0] = rec
+		heap.Fix(h, 0)
+	}
+}
+
+//spill 将内存中的数据排序后写入临时文件，有topN时只写入前topN行
+func (s *externalSorter) spill() error {
+	if len(s.records) == 0 {
+		return nil
+	}
+	s.sortRecords()
+	records := s.records
+	if s.cfg.topN > 0 && len(records) > s.cfg.topN {
+		records = records[:s.cfg.topN]
+	}
+	file, err := ioutil.TempFile(s.cfg.tempDir, "json_filter_sort_*")
+	if err != nil {
+		return err
+	}
+	s.files = append(s.files, file)
+	w := bufio.NewWriter(file)
+	enc := gob.NewEncoder(w)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	s.records = nil
+	s.memUsed = 0
+	return nil
+}
+
+//Sort 结束添加数据，准备按顺序读取
+func (s *externalSorter) Sort() error {
+	if len(s.files) == 0 {
+		s.sortRecords()
+		return nil
+	}
+	if err := s.spill(); err != nil {
+		return err
+	}
+	s.runs = &runHeap{items: s.items}
+	for i, file := range s.files {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		r := &sortRun{
+			index: i,
+			dec:   gob.NewDecoder(bufio.NewReader(file)),
+		}
+		ok, err := r.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			s.runs.runs = append(s.runs.runs, r)
+		}
+	}
+	heap.Init(s.runs)
+	return nil
+}
+
+//Next 按顺序返回下一行数据
+func (s *externalSorter) Next() (sortRecord, bool, error) {
+	if s.runs == nil {
+		if s.index >= len(s.records) {
+			return sortRecord{}, false, nil
+		}
+		rec := s.records[s.index]
+		s.records[s.index] = sortRecord{}
+		s.index++
+		return rec, true, nil
+	}
+	if s.runs.Len() == 0 {
+		return sortRecord{}, false, nil
+	}
+	r := s.runs.runs[0]
+	rec := r.current
+	ok, err := r.next()
+	if err != nil {
+		return sortRecord{}, false, err
+	}
+	if ok {
+		heap.Fix(s.runs, 0)
+	} else {
+		heap.Pop(s.runs)
+	}
+	return rec, true, nil
+}
+
+//Close 删除临时文件
+func (s *externalSorter) Close() error {
+	var firstErr error
+	for _, file := range s.files {
+		if err := file.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if err := os.Remove(file.Name()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	s.files = nil
+	s.records = nil
+	s.runs = nil
+	return firstErr
+}
+
+//sortRun 临时文件中一段已排好序的数据
+type sortRun struct {
+	index   int
+	dec     *gob.Decoder
+	current sortRecord
+}
+
+func (r *sortRun) next() (bool, error) {
+	r.current = sortRecord{}
+	if err := r.dec.Decode(&r.current); err != nil {
+		if err == io.EOF {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+type runHeap struct {
+	items []OrderByItem
+	runs  []*sortRun
+}
+
+func (h *runHeap) Len() int {
+	return len(h.runs)
+}
+
+func (h *runHeap) Less(i, j int) bool {
+	c := compareKeys(h.items, h.runs[i].current.Keys, h.runs[j].current.Keys)
+	if c != 0 {
+		return c < 0
+	}
+	//相同时先写入的数据排在前面，保证排序稳定
+	return h.runs[i].index < h.runs[j].index
+}
+
+func (h *runHeap) Swap(i, j int) {
+	h.runs[i], h.runs[j] = h.runs[j], h.runs[i]
+}
+
+func (h *runHeap) Push(x interface{}) {
+	h.runs = append(h.runs, x.(*sortRun))
+}
+
+func (h *runHeap) Pop() interface{} {
+	old := h.runs
+	n := len(old)
+	x := old[n-1]
+	h.runs = old[:n-1]
+	return x
+}
+
+//topNHeap 以最大的一行为堆顶
+type topNHeap struct {
+	s *externalSorter
+}
+
+func (h topNHeap) Len() int {
+	return len(h.s.records)
+}
+
+func (h topNHeap) Less(i, j int) bool {
+	return h.s.less(h.s.records[j], h.s.records[i])
+}
+
+func (h topNHeap) Swap(i, j int) {
+	h.s.records[i], h.s.records[j] = h.s.records[j], h.s.records[i]
+}
+
+func (h topNHeap) Push(x interface{}) {
+	h.s.records = append(h.s.records, x.(sortRecord))
+}
+
+func (h topNHeap) Pop() interface{} {
+	old := h.s.records
+	n := len(old)
+	x := old[n-1]
+	h.s.records = old[:n-1]
+	return x
+}
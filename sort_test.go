@@ -0,0 +1,144 @@
+package json_filter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+//TestOrderBySpill 内存上限很小时排序的数据写入多个临时文件，归并后仍然稳定，关闭后删除临时文件
+func TestOrderBySpill(t *testing.T) {
+	dir, err := ioutil.TempDir("", "json_filter_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const rows = 500
+	var input strings.Builder
+	for i := 0; i < rows; i++ {
+		fmt.Fprintf(&input, `{"k":%d,"i":%d}`+"\n", i%7, i)
+	}
+	for _, order := range []string{"asc", "desc"} {
+		f, err := NewJSONFilterWithConfig(FilterConfig{
+			Reader:          strings.NewReader(input.String()),
+			ErrWriter:       ioutil.Discard,
+			SQL:             "select k, i from t order by k " + order,
+			SortMemoryLimit: 1024,
+			TempDir:         dir,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var lines []string
+		for f.Next() {
+			if len(lines) == 0 {
+				files, err := ioutil.ReadDir(dir)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(files) < 2 {
+					t.Errorf("order by k %s: %d temp files, want at least 2", order, len(files))
+				}
+			}
+			line, err := f.GetData()
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines = append(lines, string(line))
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if len(lines) != rows {
+			t.Fatalf("order by k %s: got %d rows, want %d", order, len(lines), rows)
+		}
+		prevK, prevI := -1, -1
+		for _, line := range lines {
+			var k, i int
+			if _, err := fmt.Sscanf(line, `{"k":%d,"i":%d}`, &k, &i); err != nil {
+				t.Fatalf("%s: %v", line, err)
+			}
+			if prevK >= 0 && k != prevK && (order == "asc") != (k > prevK) {
+				t.Fatalf("order by k %s: %d after %d", order, k, prevK)
+			}
+			//k相同时保持输入的顺序
+			if k == prevK && i < prevI {
+				t.Fatalf("order by k %s: unstable, i=%d after i=%d", order, i, prevI)
+			}
+			prevK, prevI = k, i
+		}
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 0 {
+			t.Errorf("order by k %s: %d temp files left after Close", order, len(files))
+		}
+	}
+}
+
+//TestOrderByLimitSpill 有limit时保留的前几行超出内存上限也写入临时文件，结果与全部排序后取前几行相同
+func TestOrderByLimitSpill(t *testing.T) {
+	dir, err := ioutil.TempDir("", "json_filter_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const rows = 500
+	var input strings.Builder
+	type row struct{ k, i int }
+	all := make([]row, rows)
+	for i := 0; i < rows; i++ {
+		all[i] = row{(i * 3) % 7, i}
+		fmt.Fprintf(&input, `{"k":%d,"i":%d}`+"\n", all[i].k, i)
+	}
+	sort.SliceStable(all, func(a, b int) bool { return all[a].k > all[b].k })
+	want := make([]string, 0)
+	for _, r := range all[5:125] {
+		want = append(want, fmt.Sprintf(`{"k":%d,"i":%d}`, r.k, r.i))
+	}
+
+	f, err := NewJSONFilterWithConfig(FilterConfig{
+		Reader:          strings.NewReader(input.String()),
+		ErrWriter:       ioutil.Discard,
+		SQL:             "select k, i from t order by k desc limit 120 offset 5",
+		SortMemoryLimit: 1024,
+		TempDir:         dir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for f.Next() {
+		if len(lines) == 0 {
+			files, err := ioutil.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) < 2 {
+				t.Errorf("%d temp files, want at least 2", len(files))
+			}
+		}
+		line, err := f.GetData()
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, string(line))
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("got %q\nwant %q", lines, want)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Errorf("%d temp files left after limit", len(files))
+	}
+}
@@ -0,0 +1,250 @@
+package json_filter
+
+import (
+	stdjson "encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+//Interval 时间间隔，如interval '5 minutes'，输出为"5m0s"这样的字符串
+type Interval time.Duration
+
+func (i Interval) String() string {
+	return time.Duration(i).String()
+}
+
+func (i Interval) MarshalJSON() ([]byte, error) {
+	return stdjson.Marshal(i.String())
+}
+
+//locationGetter 提供计算时间时使用的时区
+type locationGetter interface {
+	Location() *time.Location
+}
+
+//getterLocation 取出getter的时区，没有设置时使用本地时区
+func getterLocation(getter Getter) *time.Location {
+	if g, ok := getter.(locationGetter); ok {
+		if loc := g.Location(); loc != nil {
+			return loc
+		}
+	}
+	return time.Local
+}
+
+//timeLayouts 字符串转换为时间时依次尝试的格式，没有时区的按配置的时区解析
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+//parseTimeString 按timeLayouts中的格式解析时间，ok为false时不是时间字符串
+func parseTimeString(s string, loc *time.Location) (t time.Time, ok bool) {
+	s = strings.TrimSpace(s)
+	//不是yyyy-mm-dd开头的字符串直接跳过，避免逐个格式尝试
+	if len(s) < 10 || s[4] != '-' || s[7] != '-' {
+		return time.Time{}, false
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+//toTime 将时间、时间字符串或unix时间戳(秒，可以有小数)转换为时间
+func toTime(data interface{}, loc *time.Location) (time.Time, error) {
+	switch v := data.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		if t, ok := parseTimeString(v, loc); ok {
+			return t, nil
+		}
+	default:
+		if isNumberValue(data) {
+			d, err := toNumber(data)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return unixTime(d, loc), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot convert %v to time", data)
+}
+
+//unixTime 将unix时间戳(秒)转换为时间
+func unixTime(d decimal.Decimal, loc *time.Location) time.Time {
+	sec := d.Floor()
+	nsec := d.Sub(sec).Shift(9).IntPart()
+	return time.Unix(sec.IntPart(), nsec).In(loc)
+}
+
+//unixSeconds 时间对应的unix时间戳(秒)，有小数部分时保留到纳秒
+func unixSeconds(t time.Time) decimal.Decimal {
+	return decimal.New(t.Unix(), 0).Add(decimal.New(int64(t.Nanosecond()), -9))
+}
+
+//timeOperands 一边是时间，或一边是时间字符串另一边是数字或时间字符串时，将两边都转换为时间比较，
+//ok为false时按普通的值比较
+func timeOperands(a, b interface{}, loc *time.Location) (x, y time.Time, ok bool) {
+	_, aIsTime := a.(time.Time)
+	_, bIsTime := b.(time.Time)
+	if !aIsTime && !bIsTime {
+		_, aIsStr := a.(string)
+		_, bIsStr := b.(string)
+		if !(aIsStr && (bIsStr || isNumberValue(b))) && !(bIsStr && isNumberValue(a)) {
+			return x, y, false
+		}
+	}
+	var err error
+	if x, err = toTime(a, loc); err != nil {
+		return x, y, false
+	}
+	if y, err = toTime(b, loc); err != nil {
+		return x, y, false
+	}
+	return x, y, true
+}
+
+//compareTime 比较两个时间的先后
+func compareTime(x, y time.Time) int {
+	switch {
+	case x.Before(y):
+		return -1
+	case x.After(y):
+		return 1
+	}
+	return 0
+}
+
+//addTemporal 计算时间和时间间隔的加减，sign为-1时为减法，
+//两边都不是时间或时间间隔时ok为false
+func addTemporal(a, b interface{}, sign int, loc *time.Location) (result interface{}, ok bool, err error) {
+	ta, aIsTime := a.(time.Time)
+	ia, aIsInterval := a.(Interval)
+	tb, bIsTime := b.(time.Time)
+	ib, bIsInterval := b.(Interval)
+	if !aIsTime && !aIsInterval && !bIsTime && !bIsInterval {
+		return nil, false, nil
+	}
+	switch {
+	case aIsInterval && bIsInterval:
+		return ia + Interval(sign)*ib, true, nil
+	case bIsInterval:
+		if !aIsTime {
+			if ta, err = toTime(a, loc); err != nil {
+				return nil, true, err
+			}
+		}
+		return ta.Add(time.Duration(sign) * time.Duration(ib)), true, nil
+	case aIsInterval && sign > 0:
+		if !bIsTime {
+			if tb, err = toTime(b, loc); err != nil {
+				return nil, true, err
+			}
+		}
+		return tb.Add(time.Duration(ia)), true, nil
+	case aIsTime && sign < 0:
+		if !bIsTime {
+			if tb, err = toTime(b, loc); err != nil {
+				return nil, true, err
+			}
+		}
+		return Interval(ta.Sub(tb)), true, nil
+	case bIsTime && sign < 0:
+		if ta, err = toTime(a, loc); err != nil {
+			return nil, true, err
+		}
+		return Interval(ta.Sub(tb)), true, nil
+	}
+	op := OperatorPlus
+	if sign < 0 {
+		op = OperatorMinus
+	}
+	return nil, true, fmt.Errorf("cannot calculate %v %s %v", a, op, b)
+}
+
+//intervalUnits 时间间隔支持的单位
+var intervalUnits = map[string]time.Duration{
+	"microsecond":  time.Microsecond,
+	"microseconds": time.Microsecond,
+	"us":           time.Microsecond,
+	"millisecond":  time.Millisecond,
+	"milliseconds": time.Millisecond,
+	"ms":           time.Millisecond,
+	"second":       time.Second,
+	"seconds":      time.Second,
+	"sec":          time.Second,
+	"secs":         time.Second,
+	"s":            time.Second,
+	"minute":       time.Minute,
+	"minutes":      time.Minute,
+	"min":          time.Minute,
+	"mins":         time.Minute,
+	"m":            time.Minute,
+	"hour":         time.Hour,
+	"hours":        time.Hour,
+	"h":            time.Hour,
+	"day":          24 * time.Hour,
+	"days":         24 * time.Hour,
+	"d":            24 * time.Hour,
+	"week":         7 * 24 * time.Hour,
+	"weeks":        7 * 24 * time.Hour,
+	"w":            7 * 24 * time.Hour,
+}
+
+//parseInterval 解析时间间隔，如'5 minutes'、'1 hour 30 minutes'，也支持Go的写法'1h30m'
+func parseInterval(s string) (Interval, error) {
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("empty interval")
+	}
+	if len(fields) == 1 {
+		if d, err := time.ParseDuration(fields[0]); err == nil {
+			return Interval(d), nil
+		}
+	}
+	if len(fields)%2 != 0 {
+		return 0, fmt.Errorf("invalid interval '%s'", s)
+	}
+	var total float64
+	for i := 0; i < len(fields); i += 2 {
+		n, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid interval '%s'", s)
+		}
+		unit, ok := intervalUnits[fields[i+1]]
+		if !ok {
+			return 0, fmt.Errorf("unknown interval unit %s", fields[i+1])
+		}
+		total += n * float64(unit)
+	}
+	if math.Abs(total) > math.MaxInt64 {
+		return 0, fmt.Errorf("interval '%s' out of range", s)
+	}
+	return Interval(math.Round(total)), nil
+}
+
+//NodeInterval 时间间隔常量interval '5 minutes'
+type NodeInterval struct {
+	interval Interval
+}
+
+func (n NodeInterval) Type() NodeType {
+	return NodeTypeInterval
+}
+
+func (n NodeInterval) Interface(getter Getter) (interface{}, error) {
+	return n.interval, nil
+}
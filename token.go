@@ -3,21 +3,44 @@ package json_filter
 import (
 	"fmt"
 	"strings"
+	"unicode/utf8"
 )
 
 type Token struct {
+	//Str token的内容，字符串为转义后的值，带引号的字段名为规范化后的路径
 	Str  string
 	Type TokenType
+	//Pos token在sql中的字节偏移
+	Pos int
+	//Line token所在的行，从1开始
+	Line int
+	//Column token所在的列，从1开始，按字符计算
+	Column int
 }
 
 func (t Token) String() string {
 	s := t.Str
-	if t.Type == TokenTypeString {
-		s = "'" + s + "'"
+	switch t.Type {
+	case TokenTypeString:
+		s = "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	case TokenTypeIdentifier:
+		s = formatPath(parsePath(s), true)
 	}
 	return fmt.Sprintf("%s", s)
 }
 
+//quoteIdentifier 字段名不能直接写在sql中时用双引号括起来
+func quoteIdentifier(s string) string {
+	quote := s == "" || isReserved(strings.ToLower(s))
+	for i := 0; i < len(s) && !quote; i++ {
+		quote = !isWordChar(s[i])
+	}
+	if !quote {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
 type TokenType int8
 
 const (
@@ -28,6 +51,8 @@ const (
 	TokenTypeKeyword
 	TokenTypeLeftParen
 	TokenTypeRightParen
+	//TokenTypeIdentifier 用双引号或反引号括起来的字段名，不会被当作关键字
+	TokenTypeIdentifier
 )
 
 func (nt TokenType) String() string {
@@ -46,156 +71,417 @@ func (nt TokenType) String() string {
 		return "("
 	case TokenTypeRightParen:
 		return ")"
+	case TokenTypeIdentifier:
+		return "identifier"
 	default:
 		panic("unsupported type")
 	}
 }
 
-type ParseTokenState int8
-
-const (
-	ParseStateNormal ParseTokenState = iota
-	ParseStateInOperator
-)
+//lexer 将sql拆分为token，并记录每个token的位置
+type lexer struct {
+	sql    string
+	pos    int
+	line   int
+	column int
+	tokens []*Token
+}
 
 //Parse 将sql解析成token
 func Parse(sql string) ([]*Token, error) {
-	str := sql
-	tokens := make([]*Token, 0)
-	var state ParseTokenState = ParseStateNormal
-	var stringLeftChar byte
-	var i int
-	for i = 0; i < len(str); {
-		//默认状态
-		if state == ParseStateNormal {
-			if isWhitespace(str[i]) {
-				if i > 0 {
-					t := &Token{
-						Str:  str[0:i],
-						Type: TokenTypeUnknow,
-					}
-					if isNumber(t.Str) {
-						t.Type = TokenTypeNumber
-					}
-					tokens = append(tokens, t)
-				}
-				str = str[i+1:]
-				i = 0
+	l := &lexer{
+		sql:    sql,
+		line:   1,
+		column: 1,
+		tokens: make([]*Token, 0),
+	}
+	if err := l.run(); err != nil {
+		return nil, err
+	}
+	return l.tokens, nil
+}
+
+//peekByte 返回当前位置之后第n个字节，超出范围时返回0
+func (l *lexer) peekByte(n int) byte {
+	if l.pos+n >= len(l.sql) {
+		return 0
+	}
+	return l.sql[l.pos+n]
+}
+
+//advance 前进n个字节，同时更新行号和列号
+func (l *lexer) advance(n int) {
+	for end := l.pos + n; l.pos < end; {
+		r, size := utf8.DecodeRuneInString(l.sql[l.pos:])
+		l.pos += size
+		if r == '\n' {
+			l.line++
+			l.column = 1
+		} else {
+			l.column++
+		}
+	}
+}
+
+//copyRune 将当前的字符写入b并前进
+func (l *lexer) copyRune(b *strings.Builder) {
+	_, size := utf8.DecodeRuneInString(l.sql[l.pos:])
+	b.WriteString(l.sql[l.pos : l.pos+size])
+	l.advance(size)
+}
+
+//emit 添加一个从start开始的token
+func (l *lexer) emit(start *Token, typ TokenType, str string) {
+	start.Type = typ
+	start.Str = str
+	l.tokens = append(l.tokens, start)
+}
+
+//mark 以当前位置作为token的开始位置
+func (l *lexer) mark() *Token {
+	return &Token{
+		Pos:    l.pos,
+		Line:   l.line,
+		Column: l.column,
+	}
+}
+
+func (l *lexer) errorf(offset int, format string, a ...interface{}) error {
+	return newSyntaxError(l.sql, offset, format, a...)
+}
+
+func (l *lexer) run() error {
+	for l.pos < len(l.sql) {
+		c := l.sql[l.pos]
+		t := l.mark()
+		switch {
+		case isWhitespace(c):
+			l.advance(1)
+		case c == '-' && l.peekByte(1) == '-':
+			//单行注释
+			for l.pos < len(l.sql) && l.sql[l.pos] != '\n' {
+				l.advance(1)
+			}
+		case c == '/' && l.peekByte(1) == '*':
+			//多行注释
+			end := strings.Index(l.sql[l.pos+2:], "*/")
+			if end == -1 {
+				return l.errorf(t.Pos, "unterminated comment")
+			}
+			l.advance(end + 4)
+		case c == '\'' && l.afterEscape() && strings.HasPrefix(l.sql[l.pos:], `'\'`) && l.peekByte(3) != '\'':
+			//escape '\'，以反斜杠作为like的转义字符
+			l.advance(3)
+			l.emit(t, TokenTypeString, `\`)
+		case c == '\'':
+			s, err := l.scanString()
+			if err != nil {
+				return err
+			}
+			l.emit(t, TokenTypeString, s)
+		case c == '(':
+			l.advance(1)
+			l.emit(t, TokenTypeLeftParen, "(")
+		case c == ')':
+			l.advance(1)
+			l.emit(t, TokenTypeRightParen, ")")
+		case c == ',' || c == ';':
+			l.advance(1)
+			l.emit(t, TokenTypeKeyword, string(c))
+		case c == '-' && isNumberStart(l.peekByte(1), l.peekByte(2)) && !l.afterOperand():
+			//前面不是操作数时，-紧跟数字为负数
+			l.advance(1)
+			num, ok := l.scanNumber()
+			if !ok {
+				return l.errorf(t.Pos, "invalid number")
+			}
+			l.emit(t, TokenTypeNumber, "-"+num)
+		case isOperatorChar(c):
+			op := l.scanOperator()
+			if op == "" {
+				return l.errorf(t.Pos, "unexpected character %c", c)
+			}
+			l.emit(t, TokenTypeOperator, op)
+		case isNumberStart(c, l.peekByte(1)):
+			num, ok := l.scanNumber()
+			if ok {
+				l.emit(t, TokenTypeNumber, num)
 				continue
 			}
-			switch str[i] {
-			case '\'', '"':
-				stringLeftChar = str[i]
-				if i > 0 {
-					t := &Token{
-						Str:  str[0:i],
-						Type: TokenTypeUnknow,
-					}
-					tokens = append(tokens, t)
-				}
-				str = str[i+1:]
-				i = 0
-				stringRightCharIndex := strings.Index(str, string(stringLeftChar))
-				if stringRightCharIndex == -1 {
-					return nil, fmt.Errorf("sql error: %s", sql)
-				}
-				t := &Token{
-					Str:  str[0:stringRightCharIndex],
-					Type: TokenTypeString,
-				}
-				tokens = append(tokens, t)
-				str = str[stringRightCharIndex+1:]
-				i = 0
-			case '(', ')':
-				if i > 0 {
-					t := &Token{
-						Str:  str[0:i],
-						Type: TokenTypeUnknow,
-					}
-					if isNumber(t.Str) {
-						t.Type = TokenTypeNumber
-					}
-					tokens = append(tokens, t)
-				}
-				t := &Token{
-					Type: TokenTypeOperator,
-					Str:  string(str[i]),
-				}
-				if str[i] == '(' {
-					t.Type = TokenTypeLeftParen
-				} else {
-					t.Type = TokenTypeRightParen
-				}
-				tokens = append(tokens, t)
-				str = str[i+1:]
-				i = 0
-			case ',':
-				if i > 0 {
-					t := &Token{
-						Str:  str[0:i],
-						Type: TokenTypeUnknow,
-					}
-					if isNumber(t.Str) {
-						t.Type = TokenTypeNumber
-					}
-					tokens = append(tokens, t)
-				}
-				tokens = append(tokens, &Token{
-					Str:  ",",
-					Type: TokenTypeKeyword,
-				})
-				str = str[i+1:]
-				i = 0
-			case '+', '-', '*', '/', '%', '=',
-				'>', '!', '<': //'<=','>=','!=','<>'
-				if i > 0 {
-					tokens = append(tokens, &Token{
-						Str:  str[0:i],
-						Type: TokenTypeUnknow,
-					})
-				}
-				state = ParseStateInOperator
-				i++
-			default:
-				if i == len(str)-1 {
-					t := Token{
-						Str:  str[0:],
-						Type: TokenTypeUnknow,
-					}
-					if isNumber(t.Str) {
-						t.Type = TokenTypeNumber
-					}
-					tokens = append(tokens, &t)
+			//数字开头的字段名，如1st
+			word, err := l.scanWord()
+			if err != nil {
+				return err
+			}
+			l.emit(t, TokenTypeUnknow, num+word)
+		default:
+			word, err := l.scanWord()
+			if err != nil {
+				return err
+			}
+			typ := TokenTypeUnknow
+			if strings.ContainsAny(l.sql[t.Pos:l.pos], "\"`") {
+				typ = TokenTypeIdentifier
+			}
+			l.emit(t, typ, word)
+		}
+	}
+	return nil
+}
+
+//afterOperand 上一个token是否为操作数，用于区分减号和负号
+func (l *lexer) afterOperand() bool {
+	if len(l.tokens) == 0 {
+		return false
+	}
+	prev := l.tokens[len(l.tokens)-1]
+	switch prev.Type {
+	case TokenTypeString, TokenTypeNumber, TokenTypeIdentifier, TokenTypeRightParen:
+		return true
+	case TokenTypeUnknow:
+		word := strings.ToLower(prev.Str)
+		return !isReserved(word) && !openingKeywords[word] || closingKeywords[word]
+	}
+	return false
+}
+
+//afterEscape 上一个token是否为escape
+func (l *lexer) afterEscape() bool {
+	if len(l.tokens) == 0 {
+		return false
+	}
+	prev := l.tokens[len(l.tokens)-1]
+	return isKeywordToken(prev, KeywordEscape)
+}
+
+//closingKeywords 可以作为操作数结尾的保留字，后面的-是减号
+var closingKeywords = map[string]bool{
+	KeywordNULL:  true,
+	KeywordTrue:  true,
+	KeywordFalse: true,
+}
+
+//openingKeywords 后面跟着表达式的非保留字，后面的-是负号
+var openingKeywords = map[string]bool{
+	KeywordCase: true,
+	KeywordWhen: true,
+	KeywordThen: true,
+	KeywordElse: true,
+}
+
+//scanString 解析单引号括起来的字符串，支持''和反斜杠转义
+func (l *lexer) scanString() (string, error) {
+	start := l.pos
+	l.advance(1)
+	var b strings.Builder
+	for l.pos < len(l.sql) {
+		c := l.sql[l.pos]
+		switch {
+		case c == '\'' && l.peekByte(1) == '\'':
+			b.WriteByte('\'')
+			l.advance(2)
+		case c == '\'':
+			l.advance(1)
+			return b.String(), nil
+		case c == '\\' && l.pos+1 < len(l.sql):
+			b.WriteString(unescape(l.sql[l.pos+1]))
+			l.advance(2)
+		default:
+			l.copyRune(&b)
+		}
+	}
+	return "", l.errorf(start, "unterminated string")
+}
+
+//unescape 返回反斜杠转义后的字符，不认识的转义保留反斜杠，如正则中的\d
+func unescape(c byte) string {
+	switch c {
+	case 'n':
+		return "\n"
+	case 't':
+		return "\t"
+	case 'r':
+		return "\r"
+	case '0':
+		return "\x00"
+	case '\\', '\'', '"':
+		return string(c)
+	}
+	return "\\" + string(c)
+}
+
+//scanNumber 解析数字，支持小数和科学计数法，数字后紧跟字母时返回false
+func (l *lexer) scanNumber() (string, bool) {
+	start := l.pos
+	digits := func() {
+		for l.pos < len(l.sql) && l.sql[l.pos] >= '0' && l.sql[l.pos] <= '9' {
+			l.advance(1)
+		}
+	}
+	digits()
+	if l.peekByte(0) == '.' {
+		l.advance(1)
+		digits()
+	}
+	if c := l.peekByte(0); c == 'e' || c == 'E' {
+		n := 1
+		if sign := l.peekByte(1); sign == '+' || sign == '-' {
+			n++
+		}
+		if d := l.peekByte(n); d >= '0' && d <= '9' {
+			l.advance(n)
+			digits()
+		}
+	}
+	num := l.sql[start:l.pos]
+	if l.pos < len(l.sql) && isWordChar(l.sql[l.pos]) {
+		return num, false
+	}
+	return num, true
+}
+
+//scanWord 解析关键字或字段名，字段名中可以包含用双引号或反引号括起来的部分，
+//以及数组的下标、切片、方括号括起来的字段名、*和..，返回规范化后的路径，如data."http.status"、data.items[0]
+func (l *lexer) scanWord() (string, error) {
+	var b strings.Builder
+	segment := strings.Builder{}
+	quoted := false
+	flush := func() {
+		if quoted {
+			b.WriteString(quotePathSegment(segment.String()))
+		} else {
+			b.WriteString(segment.String())
+		}
+		segment.Reset()
+		quoted = false
+	}
+	for l.pos < len(l.sql) {
+		c := l.sql[l.pos]
+		switch {
+		case c == '"' || c == '`':
+			s, err := l.scanQuoted(c)
+			if err != nil {
+				return "", err
+			}
+			segment.WriteString(s)
+			quoted = true
+		case c == '.':
+			flush()
+			b.WriteByte('.')
+			l.advance(1)
+		case c == '[':
+			seg, n, ok := parseBracket(l.sql[l.pos:])
+			if !ok {
+				//特殊的字段名[keys]
+				if b.Len() == 0 && segment.Len() == 0 && strings.HasPrefix(l.sql[l.pos:], "[keys]") {
+					segment.WriteString("[keys]")
+					l.advance(len("[keys]"))
+					break
 				}
-				i++
+				return "", l.errorf(l.pos, "invalid array index or slice %s", bracketText(l.sql[l.pos:]))
 			}
-		} else if state == ParseStateInOperator { // 操作符中
-			if isWhitespace(str[i]) && str[i-1] == '*' {
-				tokens = append(tokens, &Token{
-					Str:  string('*'),
-					Type: TokenTypeUnknow,
-				})
-				str = str[i:]
-			} else if str[i] == '=' || str[i] == '>' {
-				tokens = append(tokens, &Token{
-					Str:  str[i-1 : i+1],
-					Type: TokenTypeOperator,
-				})
-				str = str[i+1:]
+			flush()
+			if seg.kind == segmentKey {
+				if b.Len() > 0 && !strings.HasSuffix(b.String(), ".") {
+					b.WriteByte('.')
+				}
+				b.WriteString(quotePathSegment(seg.key))
 			} else {
-				tokens = append(tokens, &Token{
-					Str:  string(str[i-1]),
-					Type: TokenTypeOperator,
-				})
-				str = str[i:]
+				b.WriteString(seg.String())
+			}
+			l.advance(n)
+		case c == '*' && segment.Len() == 0 && strings.HasSuffix(b.String(), "."):
+			//.后面的*匹配所有的字段，如data.*.id
+			segment.WriteByte(c)
+			l.advance(1)
+		case c == ']' || isWordChar(c):
+			l.copyRune(&segment)
+		default:
+			flush()
+			return b.String(), nil
+		}
+	}
+	flush()
+	return b.String(), nil
+}
+
+//bracketText 返回s开头的方括号部分，用于错误信息，到]、空白或字符串末尾为止
+func bracketText(s string) string {
+	end := strings.IndexAny(s, "] \t\r\n")
+	switch {
+	case end < 0:
+		return s
+	case s[end] == ']':
+		return s[:end+1]
+	}
+	return s[:end]
+}
+
+//scanQuoted 解析双引号或反引号括起来的字段名，两个连续的引号表示引号本身
+func (l *lexer) scanQuoted(quote byte) (string, error) {
+	start := l.pos
+	l.advance(1)
+	var b strings.Builder
+	for l.pos < len(l.sql) {
+		c := l.sql[l.pos]
+		if c == quote {
+			if l.peekByte(1) == quote {
+				b.WriteByte(quote)
+				l.advance(2)
+				continue
 			}
-			state = ParseStateNormal
-			i = 0
-		} else { //未知状态
-			return nil, fmt.Errorf("unknow error: sql:%s, char:%c", str, str[0])
+			l.advance(1)
+			return b.String(), nil
+		}
+		l.copyRune(&b)
+	}
+	return "", l.errorf(start, "unterminated quoted identifier")
+}
+
+//scanOperator 解析运算符，不是合法的运算符时返回空字符串
+func (l *lexer) scanOperator() string {
+	if l.pos+3 <= len(l.sql) && l.sql[l.pos:l.pos+3] == OperatorNotIRegexp {
+		l.advance(3)
+		return OperatorNotIRegexp
+	}
+	if l.pos+2 <= len(l.sql) {
+		switch two := l.sql[l.pos : l.pos+2]; two {
+		case OperatorLessEqual, OperatorGreaterEqual, OperatorNotEqual, OperatorNotEqual2, OperatorIRegexp, OperatorNotRegexp, OperatorConcat:
+			l.advance(2)
+			return two
 		}
 	}
-	return tokens, nil
+	c := l.sql[l.pos]
+	if c == '!' || c == '|' {
+		return ""
+	}
+	l.advance(1)
+	return string(c)
+}
+
+//isNumberStart 以c、next开头时是否为数字
+func isNumberStart(c, next byte) bool {
+	return c >= '0' && c <= '9' || c == '.' && next >= '0' && next <= '9'
+}
+
+func isOperatorChar(c byte) bool {
+	switch c {
+	case '+', '-', '*', '/', '%', '=', '>', '!', '<', '~', '|':
+		return true
+	}
+	return false
+}
+
+//isWordChar 是否可以作为关键字或字段名的一部分
+func isWordChar(c byte) bool {
+	if isWhitespace(c) || isOperatorChar(c) {
+		return false
+	}
+	switch c {
+	case '\'', '"', '`', '(', ')', ',', ';', '.', '[', ']':
+		return false
+	}
+	return true
 }
 
 func isLeftParen(t *Token) bool {
@@ -205,3 +491,23 @@ func isLeftParen(t *Token) bool {
 func isRightParen(t *Token) bool {
 	return t.Type == TokenTypeRightParen
 }
+
+func isComma(t *Token) bool {
+	return t.Type == TokenTypeKeyword && t.Str == ","
+}
+
+//tokensText 将token还原为sql文本，用作表达式的名称
+func tokensText(tokens []*Token) string {
+	var b strings.Builder
+	for i, t := range tokens {
+		if i > 0 {
+			prev := tokens[i-1]
+			isCall := isLeftParen(t) && prev.Type == TokenTypeUnknow && !isKeyword(strings.ToLower(prev.Str))
+			if !isLeftParen(prev) && !isRightParen(t) && !isComma(t) && !isCall {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(t.String())
+	}
+	return b.String()
+}
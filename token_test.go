@@ -0,0 +1,101 @@
+package json_filter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMinus(t *testing.T) {
+	cases := []struct {
+		sql    string
+		tokens []string
+	}{
+		{"a -1", []string{"a", "-", "1"}},
+		{"a - 1", []string{"a", "-", "1"}},
+		{"a = -1", []string{"a", "=", "-1"}},
+		{"(-1)", []string{"(", "-1", ")"}},
+		{"f(a) -1", []string{"f", "(", "a", ")", "-", "1"}},
+		{"'x' -1", []string{"'x'", "-", "1"}},
+		{"case when a then b end -1", []string{"case", "when", "a", "then", "b", "end", "-", "1"}},
+		{"null -1", []string{"null", "-", "1"}},
+		{"true -1", []string{"true", "-", "1"}},
+		{"not -1", []string{"not", "-1"}},
+		{"and -1", []string{"and", "-1"}},
+	}
+	for _, c := range cases {
+		tokens, err := Parse(c.sql)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", c.sql, err)
+			continue
+		}
+		got := make([]string, len(tokens))
+		for i, token := range tokens {
+			got[i] = token.String()
+		}
+		if len(got) != len(c.tokens) {
+			t.Errorf("Parse(%q) = %q, want %q", c.sql, got, c.tokens)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.tokens[i] {
+				t.Errorf("Parse(%q) = %q, want %q", c.sql, got, c.tokens)
+				break
+			}
+		}
+	}
+}
+
+func TestEndMinus(t *testing.T) {
+	stmt, err := ParseStatement("select case when a > 0 then a end -1 as z from t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmt.Items) != 1 || stmt.Items[0].Name != "z" {
+		t.Fatalf("items = %+v", stmt.Items)
+	}
+	v, err := stmt.Items[0].Expr.Interface(mapGetter{"a": Number("5")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v == nil || v.(Number) != "4" {
+		t.Errorf("value = %v, want 4", v)
+	}
+}
+
+//mapGetter 测试用的Getter，按路径从map中取值
+type mapGetter map[string]interface{}
+
+func (g mapGetter) Get(key string) (interface{}, error) {
+	return g[key], nil
+}
+
+func TestComments(t *testing.T) {
+	cases := []struct {
+		sql    string
+		tokens []string
+	}{
+		{"select a -- comment\nfrom t", []string{"select", "a", "from", "t"}},
+		{"select a /* multi\nline */ from t", []string{"select", "a", "from", "t"}},
+		{"select a--b\n", []string{"select", "a"}},
+		{"select '-- not a comment', '/* x */' from t", []string{"select", "'-- not a comment'", ",", "'/* x */'", "from", "t"}},
+		{"select a - -1 /**/ from t", []string{"select", "a", "-", "-1", "from", "t"}},
+		{"select a; -- end", []string{"select", "a", ";"}},
+	}
+	for _, c := range cases {
+		tokens, err := Parse(c.sql)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", c.sql, err)
+			continue
+		}
+		got := make([]string, len(tokens))
+		for i, token := range tokens {
+			got[i] = token.String()
+		}
+		if !reflect.DeepEqual(got, c.tokens) {
+			t.Errorf("Parse(%q) = %q, want %q", c.sql, got, c.tokens)
+		}
+	}
+	if _, err := Parse("select a /* x"); err == nil || err.(*SyntaxError).Msg != "unterminated comment" {
+		t.Errorf("unterminated comment: got %v", err)
+	}
+}
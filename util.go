@@ -1,9 +1,5 @@
 package json_filter
 
-import (
-	"strconv"
-)
-
 func isWhitespace(c byte) bool {
 	return c == ' ' ||
 		c == '\t' ||
@@ -13,18 +9,12 @@ func isWhitespace(c byte) bool {
 		c == '\r'
 }
 
-func isDigitNum(c byte) bool {
-	return '0' <= c && c <= '9' || c == '.'
-}
-
-func isNumber(s string) bool {
-	_, err := strconv.ParseFloat(s, 64)
-	if err == nil {
-		return true
-	}
-	return false
+//isReserved 是否为保留字，保留字不能直接作为字段名
+func isReserved(s string) bool {
+	return keywords[s]
 }
 
+//isKeyword 是否为关键字，包括只在特定的位置作为关键字的div、interval、case等
 func isKeyword(s string) bool {
 	_, ok := keywords[s]
 	return ok